@@ -0,0 +1,221 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+// conversionDataAnnotation 保存转换为 v1alpha1 时无法表示的 v1beta1 字段。
+// v1alpha1 的客户端读取后再写回时，这些字段会从注解中恢复，不会丢失。
+const conversionDataAnnotation = "cache.swxfll.com/conversion-data"
+
+// conversionData 是 conversionDataAnnotation 的内容。
+// 只保存 spec，status 由 controller 在每次调和时重新计算，不需要保存。
+type conversionData struct {
+	Spec v1beta1.SwxfllSpec `json:"spec"`
+}
+
+var _ conversion.Convertible = &Swxfll{}
+
+// ConvertTo converts this Swxfll to the Hub version (v1beta1).
+func (src *Swxfll) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1beta1.Swxfll)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 Swxfll but got a %T", dstRaw)
+	}
+
+	// 先恢复上次转换时保存的 v1beta1 字段，再用 v1alpha1 中能表示的字段覆盖它们
+	restored := &conversionData{}
+	if data, ok := src.Annotations[conversionDataAnnotation]; ok {
+		if err := json.Unmarshal([]byte(data), restored); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", conversionDataAnnotation, err)
+		}
+	}
+
+	dst.ObjectMeta = src.ObjectMeta
+	if _, ok := src.Annotations[conversionDataAnnotation]; ok {
+		dst.Annotations = make(map[string]string, len(src.Annotations)-1)
+		for k, v := range src.Annotations {
+			if k != conversionDataAnnotation {
+				dst.Annotations[k] = v
+			}
+		}
+		if len(dst.Annotations) == 0 {
+			dst.Annotations = nil
+		}
+	}
+
+	dst.Spec = restored.Spec
+	dst.Spec.Size = src.Spec.Size
+	dst.Spec.Image = src.Spec.Image
+	dst.Spec.Version = src.Spec.Version
+
+	if src.Spec.Memcached != nil {
+		dst.Spec.Memcached = &v1beta1.MemcachedSpec{
+			MemoryMB:       src.Spec.Memcached.MemoryMB,
+			MaxConnections: src.Spec.Memcached.MaxConnections,
+			MaxItemSize:    src.Spec.Memcached.MaxItemSize,
+			Threads:        src.Spec.Memcached.Threads,
+			Verbosity:      src.Spec.Memcached.Verbosity,
+			ExtraArgs:      src.Spec.Memcached.ExtraArgs,
+		}
+	} else {
+		dst.Spec.Memcached = nil
+	}
+
+	if src.Spec.ContainerPort != 0 || src.Spec.Service != nil {
+		if dst.Spec.Networking == nil {
+			dst.Spec.Networking = &v1beta1.NetworkingSpec{}
+		}
+	}
+	if dst.Spec.Networking != nil {
+		dst.Spec.Networking.Port = src.Spec.ContainerPort
+		dst.Spec.Networking.Service = nil
+		if svc := src.Spec.Service; svc != nil {
+			dst.Spec.Networking.Service = &v1beta1.ServiceSpec{
+				Type:        svc.Type,
+				Annotations: svc.Annotations,
+				Port:        svc.Port,
+				NodePort:    svc.NodePort,
+			}
+		}
+	}
+
+	if src.Spec.Resources != nil && dst.Spec.Scheduling == nil {
+		dst.Spec.Scheduling = &v1beta1.SchedulingSpec{}
+	}
+	if dst.Spec.Scheduling != nil {
+		dst.Spec.Scheduling.Resources = nil
+		if res := src.Spec.Resources; res != nil {
+			dst.Spec.Scheduling.Resources = &v1beta1.ResourcesSpec{
+				Mode:            v1beta1.ResourcesMode(res.Mode),
+				OverheadPercent: res.OverheadPercent,
+				Requests:        res.Requests,
+				Limits:          res.Limits,
+			}
+		}
+	}
+
+	dst.Status = v1beta1.SwxfllStatus{
+		Conditions:     src.Status.Conditions,
+		CurrentVersion: src.Status.CurrentVersion,
+	}
+
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1beta1) to this version.
+func (dst *Swxfll) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1beta1.Swxfll)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 Swxfll but got a %T", srcRaw)
+	}
+
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec = SwxfllSpec{
+		Size:    src.Spec.Size,
+		Image:   src.Spec.Image,
+		Version: src.Spec.Version,
+	}
+	if mc := src.Spec.Memcached; mc != nil {
+		dst.Spec.Memcached = &MemcachedSpec{
+			MemoryMB:       mc.MemoryMB,
+			MaxConnections: mc.MaxConnections,
+			MaxItemSize:    mc.MaxItemSize,
+			Threads:        mc.Threads,
+			Verbosity:      mc.Verbosity,
+			ExtraArgs:      mc.ExtraArgs,
+		}
+	}
+	if networking := src.Spec.Networking; networking != nil {
+		dst.Spec.ContainerPort = networking.Port
+		if svc := networking.Service; svc != nil {
+			dst.Spec.Service = &ServiceSpec{
+				Type:        svc.Type,
+				Annotations: svc.Annotations,
+				Port:        svc.Port,
+				NodePort:    svc.NodePort,
+			}
+		}
+	}
+	if scheduling := src.Spec.Scheduling; scheduling != nil && scheduling.Resources != nil {
+		res := scheduling.Resources
+		dst.Spec.Resources = &ResourcesSpec{
+			Mode:            ResourcesMode(res.Mode),
+			OverheadPercent: res.OverheadPercent,
+			Requests:        res.Requests,
+			Limits:          res.Limits,
+		}
+	}
+
+	dst.Status = SwxfllStatus{
+		Conditions:     src.Status.Conditions,
+		CurrentVersion: src.Status.CurrentVersion,
+	}
+
+	// 保存 v1alpha1 无法表示的 spec 字段，转换回 v1beta1 时用于恢复它们
+	unrepresentable := unrepresentableSpec(&src.Spec)
+	if equality.Semantic.DeepEqual(unrepresentable, &v1beta1.SwxfllSpec{}) {
+		return nil
+	}
+	data, err := json.Marshal(&conversionData{Spec: *unrepresentable})
+	if err != nil {
+		return fmt.Errorf("failed to save %s: %w", conversionDataAnnotation, err)
+	}
+	annotations := make(map[string]string, len(src.Annotations)+1)
+	for k, v := range src.Annotations {
+		annotations[k] = v
+	}
+	annotations[conversionDataAnnotation] = string(data)
+	dst.Annotations = annotations
+
+	return nil
+}
+
+// unrepresentableSpec 返回 spec 中 v1alpha1 无法表示的字段，v1alpha1 中有对应字段的部分被清空。
+// ConvertTo 会根据 v1alpha1 的字段重新创建的空 networking 和 scheduling 也不保存。
+func unrepresentableSpec(spec *v1beta1.SwxfllSpec) *v1beta1.SwxfllSpec {
+	out := spec.DeepCopy()
+	out.Size = 0
+	out.Image = ""
+	out.Version = ""
+	out.Memcached = nil
+	if n := out.Networking; n != nil {
+		recreated := n.Port != 0 || n.Service != nil
+		n.Port = 0
+		n.Service = nil
+		if recreated && equality.Semantic.DeepEqual(n, &v1beta1.NetworkingSpec{}) {
+			out.Networking = nil
+		}
+	}
+	if sc := out.Scheduling; sc != nil {
+		recreated := sc.Resources != nil
+		sc.Resources = nil
+		if recreated && equality.Semantic.DeepEqual(sc, &v1beta1.SchedulingSpec{}) {
+			out.Scheduling = nil
+		}
+	}
+	return out
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+var _ = Describe("Swxfll Conversion", func() {
+	newSwxfll := func(name string) *Swxfll {
+		return &Swxfll{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				Annotations: map[string]string{"example.com/owner": "team-cache"},
+			},
+			Spec: SwxfllSpec{
+				Size:          3,
+				ContainerPort: 11211,
+				Version:       "1.6.22-alpine",
+				Memcached: &MemcachedSpec{
+					MemoryMB:       256,
+					MaxConnections: 2048,
+					MaxItemSize:    ptrTo(resource.MustParse("2Mi")),
+					Threads:        4,
+					Verbosity:      ptrTo(int32(2)),
+					ExtraArgs:      []string{"-R", "40"},
+				},
+				Resources: &ResourcesSpec{
+					Mode:            ResourcesModeAuto,
+					OverheadPercent: ptrTo(int32(30)),
+					Requests:        corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+				},
+				Service: &ServiceSpec{
+					Type:        corev1.ServiceTypeNodePort,
+					Annotations: map[string]string{"example.com/lb": "internal"},
+					Port:        11311,
+					NodePort:    31211,
+				},
+			},
+			Status: SwxfllStatus{
+				Conditions: []metav1.Condition{{
+					Type:               "Available",
+					Status:             metav1.ConditionTrue,
+					Reason:             "Reconciling",
+					Message:            "ok",
+					LastTransitionTime: metav1.Now(),
+				}},
+				CurrentVersion: "1.6.22-alpine",
+			},
+		}
+	}
+
+	Context("When converting between v1alpha1 and v1beta1", func() {
+		It("Should group the v1alpha1 fields into the v1beta1 sections", func() {
+			src := newSwxfll("grouped")
+			hub := &v1beta1.Swxfll{}
+			Expect(src.ConvertTo(hub)).To(Succeed())
+
+			Expect(hub.Spec.Size).To(Equal(int32(3)))
+			Expect(hub.Spec.Version).To(Equal("1.6.22-alpine"))
+			Expect(hub.Spec.Memcached.MemoryMB).To(Equal(int32(256)))
+			Expect(hub.Spec.Networking.Port).To(Equal(int32(11211)))
+			Expect(hub.Spec.Networking.Service.NodePort).To(Equal(int32(31211)))
+			Expect(hub.Spec.Scheduling.Resources.OverheadPercent).To(Equal(ptrTo(int32(30))))
+			Expect(hub.Status.CurrentVersion).To(Equal("1.6.22-alpine"))
+		})
+
+		It("Should round-trip a v1alpha1 object losslessly", func() {
+			for _, src := range []*Swxfll{newSwxfll("full"), {ObjectMeta: metav1.ObjectMeta{Name: "empty"}}} {
+				hub := &v1beta1.Swxfll{}
+				Expect(src.ConvertTo(hub)).To(Succeed())
+
+				dst := &Swxfll{}
+				Expect(dst.ConvertFrom(hub)).To(Succeed())
+				Expect(dst.Spec).To(Equal(src.Spec))
+				Expect(dst.Status).To(Equal(src.Status))
+				// v1alpha1 能表示全部字段时不需要保存任何数据
+				Expect(dst.ObjectMeta).To(Equal(src.ObjectMeta))
+			}
+		})
+
+		It("Should restore the v1beta1 fields v1alpha1 cannot represent", func() {
+			hub := &v1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{Name: "hub"},
+				Spec: v1beta1.SwxfllSpec{
+					Size:       2,
+					Networking: &v1beta1.NetworkingSpec{},
+					Scheduling: &v1beta1.SchedulingSpec{},
+				},
+			}
+
+			spoke := &Swxfll{}
+			Expect(spoke.ConvertFrom(hub)).To(Succeed())
+			Expect(spoke.Spec.ContainerPort).To(BeZero())
+			Expect(spoke.Spec.Resources).To(BeNil())
+
+			restored := &v1beta1.Swxfll{}
+			Expect(spoke.ConvertTo(restored)).To(Succeed())
+			Expect(restored.Spec).To(Equal(hub.Spec))
+			Expect(restored.Annotations).NotTo(HaveKey(conversionDataAnnotation))
+		})
+
+		It("Should only save the spec fields v1alpha1 cannot represent", func() {
+			hub := &v1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{Name: "saved"},
+				Spec: v1beta1.SwxfllSpec{
+					Size:       2,
+					Memcached:  &v1beta1.MemcachedSpec{MemoryMB: 128},
+					Networking: &v1beta1.NetworkingSpec{Port: 11211},
+					Proxy:      &v1beta1.ProxySpec{Enabled: true},
+				},
+				Status: v1beta1.SwxfllStatus{
+					CurrentVersion: "1.6.22-alpine",
+					Endpoints:      []string{"10.0.0.1:11211"},
+				},
+			}
+
+			spoke := &Swxfll{}
+			Expect(spoke.ConvertFrom(hub)).To(Succeed())
+			Expect(spoke.Annotations).To(HaveKeyWithValue(conversionDataAnnotation, `{"spec":{"proxy":{"enabled":true}}}`))
+
+			restored := &v1beta1.Swxfll{}
+			Expect(spoke.ConvertTo(restored)).To(Succeed())
+			Expect(restored.Spec).To(Equal(hub.Spec))
+			Expect(restored.Status).To(Equal(v1beta1.SwxfllStatus{CurrentVersion: "1.6.22-alpine"}))
+		})
+
+		It("Should let changes made through v1alpha1 win over the saved v1beta1 fields", func() {
+			hub := &v1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{Name: "changed"},
+				Spec: v1beta1.SwxfllSpec{
+					Size:       2,
+					Networking: &v1beta1.NetworkingSpec{Port: 11211, Service: &v1beta1.ServiceSpec{Port: 11311}},
+				},
+			}
+
+			spoke := &Swxfll{}
+			Expect(spoke.ConvertFrom(hub)).To(Succeed())
+			spoke.Spec.Size = 4
+			spoke.Spec.Service = nil
+
+			restored := &v1beta1.Swxfll{}
+			Expect(spoke.ConvertTo(restored)).To(Succeed())
+			Expect(restored.Spec.Size).To(Equal(int32(4)))
+			Expect(restored.Spec.Networking).To(Equal(&v1beta1.NetworkingSpec{Port: 11211}))
+		})
+	})
+
+	Context("When serving v1alpha1 objects stored as v1beta1", func() {
+		It("Should keep existing v1alpha1 clients working", func() {
+			src := newSwxfll("served")
+			src.Status = SwxfllStatus{}
+			Expect(k8sClient.Create(ctx, src)).To(Succeed())
+
+			By("Reading the object back as v1beta1")
+			hub := &v1beta1.Swxfll{}
+			key := types.NamespacedName{Name: src.Name, Namespace: src.Namespace}
+			Expect(k8sClient.Get(ctx, key, hub)).To(Succeed())
+			Expect(hub.Spec.Networking.Port).To(Equal(int32(11211)))
+			Expect(hub.Spec.Networking.Service.Port).To(Equal(int32(11311)))
+			Expect(hub.Spec.Memcached.MemoryMB).To(Equal(int32(256)))
+			Expect(hub.Annotations).NotTo(HaveKey(conversionDataAnnotation))
+
+			By("Reading the object back as v1alpha1")
+			spoke := &Swxfll{}
+			Expect(k8sClient.Get(ctx, key, spoke)).To(Succeed())
+			Expect(spoke.Spec.ContainerPort).To(Equal(src.Spec.ContainerPort))
+			Expect(spoke.Spec.Service).To(Equal(src.Spec.Service))
+			Expect(spoke.Spec.Memcached.MaxItemSize.Equal(*src.Spec.Memcached.MaxItemSize)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(spoke.Status.Conditions, "Available")).To(BeFalse())
+
+			Expect(k8sClient.Delete(ctx, spoke)).To(Succeed())
+		})
+
+		It("Should serve v1beta1 objects of any size as v1alpha1", func() {
+			hub := &v1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{Name: "served-large", Namespace: "default"},
+				Spec:       v1beta1.SwxfllSpec{Size: 8},
+			}
+			Expect(k8sClient.Create(ctx, hub)).To(Succeed())
+
+			spoke := &Swxfll{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: hub.Name, Namespace: hub.Namespace}, spoke)).To(Succeed())
+			Expect(spoke.Spec.Size).To(Equal(int32(8)))
+
+			By("Writing the object back through v1alpha1")
+			spoke.Spec.Size = 9
+			Expect(k8sClient.Update(ctx, spoke)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, spoke)).To(Succeed())
+		})
+
+		It("Should apply the v1beta1 admission webhooks to v1alpha1 objects", func() {
+			By("Defaulting an empty v1alpha1 spec")
+			swxfll := &Swxfll{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "served-defaults",
+					Namespace: "default",
+				},
+			}
+			Expect(k8sClient.Create(ctx, swxfll)).To(Succeed())
+			Expect(swxfll.Spec.Size).To(Equal(int32(1)))
+			Expect(swxfll.Spec.ContainerPort).To(Equal(v1beta1.DefaultContainerPort))
+			Expect(swxfll.Spec.Version).To(Equal(v1beta1.DefaultVersion))
+			Expect(k8sClient.Delete(ctx, swxfll)).To(Succeed())
+
+			By("Rejecting an invalid v1alpha1 spec")
+			invalid := newSwxfll("served-invalid")
+			invalid.Spec.ContainerPort = v1beta1.MetricsPort
+			Expect(k8sClient.Create(ctx, invalid)).To(MatchError(ContainSubstring("reserved for metrics")))
+		})
+	})
+})
@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -32,16 +34,142 @@ type SwxfllSpec struct {
 	// The following markers will use OpenAPI v3 schema to validate the value
 	// More info: https://book.kubebuilder.io/reference/markers/crd-validation.html
 	// +kubebuilder:validation:Minimum=1
-	// +kubebuilder:validation:Maximum=5
-	// +kubebuilder:validation:ExclusiveMaximum=false
 
-	// Size defines the number of Memcached instances
+	// Size defines the number of Memcached instances. It has no maximum, like in v1beta1, so that every
+	// v1beta1 object can be served as v1alpha1.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	Size int32 `json:"size,omitempty"`
 
 	// Port defines the port that will be used to init the container with the image
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	ContainerPort int32 `json:"containerPort,omitempty"`
+
+	// Image overrides the operand image configured on the operator (SWXFLL_IMAGE), e.g. registry.example.com/memcached:1.6.23
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Image string `json:"image,omitempty"`
+
+	// Version selects the operand version by replacing the tag of the default image.
+	// It must be one of the versions supported by the operator (SWXFLL_SUPPORTED_VERSIONS).
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,62}$`
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Version string `json:"version,omitempty"`
+
+	// Memcached configures the memcached process running in each instance
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Memcached *MemcachedSpec `json:"memcached,omitempty"`
+
+	// Resources configures the compute resources of the memcached container.
+	// Defaults to Auto mode, which derives the memory request and limit from memcached.memoryMB.
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Resources *ResourcesSpec `json:"resources,omitempty"`
+
+	// Service configures the ClusterIP and headless Services created for the Memcached instances
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Service *ServiceSpec `json:"service,omitempty"`
+}
+
+// MemcachedSpec defines the tuning parameters rendered into the memcached command line
+type MemcachedSpec struct {
+	// MemoryMB is the memory in megabytes memcached may use for item storage (-m)
+	// +kubebuilder:validation:Minimum=8
+	// +kubebuilder:validation:Maximum=65536
+	// +kubebuilder:default=64
+	// +optional
+	MemoryMB int32 `json:"memoryMB,omitempty"`
+
+	// MaxConnections is the maximum number of simultaneous client connections (-c)
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65536
+	// +optional
+	MaxConnections int32 `json:"maxConnections,omitempty"`
+
+	// MaxItemSize is the largest item memcached will store (-I), e.g. 1Mi. Must be between 1Ki and 1Gi
+	// and no larger than half of MemoryMB.
+	// +optional
+	MaxItemSize *resource.Quantity `json:"maxItemSize,omitempty"`
+
+	// Threads is the number of worker threads (-t)
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=64
+	// +optional
+	Threads int32 `json:"threads,omitempty"`
+
+	// Verbosity of the memcached log, from 0 (quiet) to 3 (-vvv). Defaults to 1.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=3
+	// +optional
+	Verbosity *int32 `json:"verbosity,omitempty"`
+
+	// ExtraArgs are appended to the memcached command line as-is
+	// +optional
+	ExtraArgs []string `json:"extraArgs,omitempty"`
+}
+
+// ResourcesMode selects how the container resources are computed
+// +kubebuilder:validation:Enum=Auto;Manual
+type ResourcesMode string
+
+const (
+	// ResourcesModeAuto derives the memory request and limit from memcached.memoryMB plus OverheadPercent
+	ResourcesModeAuto ResourcesMode = "Auto"
+	// ResourcesModeManual uses Requests and Limits as-is
+	ResourcesModeManual ResourcesMode = "Manual"
+)
+
+// ResourcesSpec defines the compute resources of the memcached container
+type ResourcesSpec struct {
+	// Mode selects how the container resources are computed. In Auto mode the memory request and limit are
+	// derived from memcached.memoryMB and any memory set in Requests or Limits is ignored; other resources
+	// such as cpu are taken from Requests and Limits.
+	// +kubebuilder:default=Auto
+	// +optional
+	Mode ResourcesMode `json:"mode,omitempty"`
+
+	// OverheadPercent is added on top of memcached.memoryMB in Auto mode to account for connection buffers,
+	// the hash table and the process itself, so the cache is never OOM-killed for using its configured memory.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=400
+	// +kubebuilder:default=25
+	// +optional
+	OverheadPercent *int32 `json:"overheadPercent,omitempty"`
+
+	// Requests describes the minimum amount of compute resources required
+	// +optional
+	Requests corev1.ResourceList `json:"requests,omitempty"`
+
+	// Limits describes the maximum amount of compute resources allowed
+	// +optional
+	Limits corev1.ResourceList `json:"limits,omitempty"`
+}
+
+// ServiceSpec defines how the Memcached instances are exposed inside the cluster
+type ServiceSpec struct {
+	// Type of the client-facing Service. The headless Service used for per-pod discovery is always ClusterIP None.
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	// +kubebuilder:default=ClusterIP
+	// +optional
+	Type corev1.ServiceType `json:"type,omitempty"`
+
+	// Annotations added to both Services, e.g. to configure a cloud load balancer
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Port exposed by the Services. Defaults to ContainerPort.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// NodePort used when Type is NodePort or LoadBalancer. Allocated by Kubernetes when not set.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	NodePort int32 `json:"nodePort,omitempty"`
 }
 
 // SwxfllStatus defines the observed state of Swxfll
@@ -61,7 +189,14 @@ type SwxfllStatus struct {
 
 	// Conditions store the status conditions of the Memcached instances
 	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +listType=map
+	// +listMapKey=type
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// CurrentVersion is the operand version every replica runs. It only changes once the rollout of a new version completes.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	CurrentVersion string `json:"currentVersion,omitempty"`
 }
 
 //+kubebuilder:object:root=true
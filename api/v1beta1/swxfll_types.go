@@ -0,0 +1,741 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// SwxfllSpec defines the desired state of Swxfll
+type SwxfllSpec struct {
+	// Size defines the number of Memcached instances. It is exposed through the scale subresource,
+	// so kubectl scale and HorizontalPodAutoscalers should target the Swxfll rather than its Deployment.
+	// When Autoscaling is set the operator adjusts it between MinReplicas and MaxReplicas.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Size int32 `json:"size,omitempty"`
+
+	// Image overrides the operand image configured on the operator (SWXFLL_IMAGE), e.g. registry.example.com/memcached:1.6.23
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Image string `json:"image,omitempty"`
+
+	// Version selects the operand version by replacing the tag of the default image.
+	// It must be one of the versions supported by the operator (SWXFLL_SUPPORTED_VERSIONS).
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,62}$`
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Version string `json:"version,omitempty"`
+
+	// WorkloadType selects how the instances are run. A Deployment replaces pods with new names and IPs, which remaps
+	// keys in clients using consistent hashing; a StatefulSet gives the pods stable identities name-0..N, resolvable
+	// through the headless Service. Changing it migrates the instances: the new workload is created first and the
+	// old one is deleted once the new one is available.
+	// +kubebuilder:default=Deployment
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	WorkloadType WorkloadType `json:"workloadType,omitempty"`
+
+	// Memcached configures the memcached process running in each instance
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Memcached *MemcachedSpec `json:"memcached,omitempty"`
+
+	// Networking configures the port memcached listens on and how the instances are exposed
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Networking *NetworkingSpec `json:"networking,omitempty"`
+
+	// Scheduling configures the pods running the instances
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Scheduling *SchedulingSpec `json:"scheduling,omitempty"`
+
+	// Monitoring configures the Prometheus exporter sidecar and the ServiceMonitor scraping it
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Monitoring *MonitoringSpec `json:"monitoring,omitempty"`
+
+	// Autoscaling lets the operator adjust Size between MinReplicas and MaxReplicas from the scraped memcached stats.
+	// Requires stats scraping to be enabled on the operator.
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+
+	// Proxy deploys an mcrouter tier in front of the instances, so clients connect to a single Service
+	// instead of tracking the pods themselves
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Proxy *ProxySpec `json:"proxy,omitempty"`
+
+	// TLS encrypts the client connections to the instances
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	TLS *TLSSpec `json:"tls,omitempty"`
+
+	// Auth requires clients to authenticate with SASL
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Auth *AuthSpec `json:"auth,omitempty"`
+
+	// AllowedClients restricts which pods can connect to the memcached port through a NetworkPolicy owned by the
+	// Swxfll. The proxy and the operator are always admitted. When empty no NetworkPolicy is created and every pod
+	// can connect.
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	AllowedClients []NetworkPeer `json:"allowedClients,omitempty"`
+
+	// DisruptionBudget configures the PodDisruptionBudget owned by the Swxfll, which limits how many instances
+	// voluntary disruptions such as node drains can evict at once. No PodDisruptionBudget is created when Size is 1,
+	// so a single instance never blocks a drain.
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	DisruptionBudget *DisruptionBudgetSpec `json:"disruptionBudget,omitempty"`
+}
+
+const (
+	// DefaultContainerPort is the memcached port used when networking.port is not set
+	DefaultContainerPort int32 = 11211
+
+	// MetricsPort is the container port reserved for exposing memcached metrics, it must not be used by the cache itself
+	MetricsPort int32 = 9150
+
+	// defaultMemoryMB is the memory memcached uses when memcached.memoryMB is not set
+	defaultMemoryMB = 64
+)
+
+// WorkloadType is the kind of workload running the instances
+// +kubebuilder:validation:Enum=Deployment;StatefulSet
+type WorkloadType string
+
+const (
+	// WorkloadTypeDeployment runs the instances in a Deployment
+	WorkloadTypeDeployment WorkloadType = "Deployment"
+	// WorkloadTypeStatefulSet runs the instances in a StatefulSet with stable pod names
+	WorkloadTypeStatefulSet WorkloadType = "StatefulSet"
+)
+
+// MemcachedSpec defines the tuning parameters rendered into the memcached command line
+type MemcachedSpec struct {
+	// MemoryMB is the memory in megabytes memcached may use for item storage (-m)
+	// +kubebuilder:validation:Minimum=8
+	// +kubebuilder:validation:Maximum=65536
+	// +kubebuilder:default=64
+	// +optional
+	MemoryMB int32 `json:"memoryMB,omitempty"`
+
+	// MaxConnections is the maximum number of simultaneous client connections (-c)
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65536
+	// +optional
+	MaxConnections int32 `json:"maxConnections,omitempty"`
+
+	// MaxItemSize is the largest item memcached will store (-I), e.g. 1Mi. Must be between 1Ki and 1Gi
+	// and no larger than half of MemoryMB.
+	// +optional
+	MaxItemSize *resource.Quantity `json:"maxItemSize,omitempty"`
+
+	// Threads is the number of worker threads (-t)
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=64
+	// +optional
+	Threads int32 `json:"threads,omitempty"`
+
+	// Verbosity of the memcached log, from 0 (quiet) to 3 (-vvv). Defaults to 1.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=3
+	// +optional
+	Verbosity *int32 `json:"verbosity,omitempty"`
+
+	// ExtraArgs are appended to the memcached command line as-is
+	// +optional
+	ExtraArgs []string `json:"extraArgs,omitempty"`
+}
+
+// NetworkingSpec defines the port memcached listens on and the Services exposing it
+type NetworkingSpec struct {
+	// Port is the container port memcached listens on. It cannot be changed once set.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// Service configures the ClusterIP and headless Services created for the Memcached instances
+	// +optional
+	Service *ServiceSpec `json:"service,omitempty"`
+}
+
+// ServiceSpec defines how the Memcached instances are exposed inside the cluster
+type ServiceSpec struct {
+	// Type of the client-facing Service. The headless Service used for per-pod discovery is always ClusterIP None.
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	// +kubebuilder:default=ClusterIP
+	// +optional
+	Type corev1.ServiceType `json:"type,omitempty"`
+
+	// Annotations added to both Services, e.g. to configure a cloud load balancer
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Port exposed by the Services. Defaults to networking.port.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// NodePort used when Type is NodePort or LoadBalancer. Allocated by Kubernetes when not set.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	NodePort int32 `json:"nodePort,omitempty"`
+}
+
+// SchedulingSpec defines the resources and placement of the pods running the instances
+type SchedulingSpec struct {
+	// Resources configures the compute resources of the memcached container.
+	// Defaults to Auto mode, which derives the memory request and limit from memcached.memoryMB.
+	// +optional
+	Resources *ResourcesSpec `json:"resources,omitempty"`
+
+	// NodeSelector restricts the pods to the nodes having all of these labels
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations lets the pods be scheduled onto nodes with matching taints
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity configures the node and pod affinity of the pods. Unless podAntiAffinity is set, the pods prefer
+	// not to share a node, then a zone, with other instances of the same Swxfll, so a single failure does not take
+	// out the whole cache. Set podAntiAffinity, possibly to {}, to replace this default.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// PriorityClassName is the PriorityClass of the pods
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// TopologySpreadConstraints spreads the pods across topology domains. A constraint without labelSelector
+	// selects the instances of the Swxfll.
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+}
+
+// ResourcesMode selects how the container resources are computed
+// +kubebuilder:validation:Enum=Auto;Manual
+type ResourcesMode string
+
+const (
+	// ResourcesModeAuto derives the memory request and limit from memcached.memoryMB plus OverheadPercent
+	ResourcesModeAuto ResourcesMode = "Auto"
+	// ResourcesModeManual uses Requests and Limits as-is
+	ResourcesModeManual ResourcesMode = "Manual"
+)
+
+// ResourcesSpec defines the compute resources of the memcached container
+type ResourcesSpec struct {
+	// Mode selects how the container resources are computed. In Auto mode the memory request and limit are
+	// derived from memcached.memoryMB and any memory set in Requests or Limits is ignored; other resources
+	// such as cpu are taken from Requests and Limits.
+	// +kubebuilder:default=Auto
+	// +optional
+	Mode ResourcesMode `json:"mode,omitempty"`
+
+	// OverheadPercent is added on top of memcached.memoryMB in Auto mode to account for connection buffers,
+	// the hash table and the process itself, so the cache is never OOM-killed for using its configured memory.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=400
+	// +kubebuilder:default=25
+	// +optional
+	OverheadPercent *int32 `json:"overheadPercent,omitempty"`
+
+	// Requests describes the minimum amount of compute resources required
+	// +optional
+	Requests corev1.ResourceList `json:"requests,omitempty"`
+
+	// Limits describes the maximum amount of compute resources allowed
+	// +optional
+	Limits corev1.ResourceList `json:"limits,omitempty"`
+}
+
+// MonitoringSpec defines how the memcached metrics are exposed to Prometheus
+type MonitoringSpec struct {
+	// Enabled adds a memcached exporter sidecar serving metrics on port 9150 to every pod, a "metrics" port to the
+	// headless Service and, when the monitoring.coreos.com ServiceMonitor CRD is installed, a ServiceMonitor
+	// selecting it. All of them are removed when monitoring is disabled.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Image of the exporter sidecar. Defaults to the exporter image configured on the operator (SWXFLL_EXPORTER_IMAGE).
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Resources of the exporter container. Defaults to a small fixed request and memory limit.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// ServiceMonitor configures the ServiceMonitor created for the instances
+	// +optional
+	ServiceMonitor *ServiceMonitorSpec `json:"serviceMonitor,omitempty"`
+
+	// AllowedScrapers restricts which pods can connect to the metrics port when spec.allowedClients is set,
+	// e.g. the Prometheus pods. When empty every pod can scrape the metrics.
+	// +optional
+	AllowedScrapers []NetworkPeer `json:"allowedScrapers,omitempty"`
+}
+
+// NetworkPeer selects the pods allowed to connect to the instances. A peer with both selectors set selects the pods
+// matching podSelector in the namespaces matching namespaceSelector.
+type NetworkPeer struct {
+	// NamespaceSelector selects the namespaces of the pods. When nil only the namespace of the Swxfll is selected;
+	// an empty selector selects all namespaces.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// PodSelector selects the pods. When nil all pods of the selected namespaces are selected.
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+}
+
+// ServiceMonitorSpec defines the ServiceMonitor created for the Prometheus Operator
+type ServiceMonitorSpec struct {
+	// Interval at which Prometheus scrapes the exporters, e.g. 30s. Defaults to the Prometheus global scrape interval.
+	// +kubebuilder:validation:Pattern=`^(0|(([0-9]+)y)?(([0-9]+)w)?(([0-9]+)d)?(([0-9]+)h)?(([0-9]+)m)?(([0-9]+)s)?(([0-9]+)ms)?)$`
+	// +optional
+	Interval string `json:"interval,omitempty"`
+
+	// Labels added to the ServiceMonitor, e.g. the labels matched by the serviceMonitorSelector of a Prometheus
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// AutoscalingSpec defines how the operator scales the instances from their memcached stats.
+// The desired size is computed like a HorizontalPodAutoscaler: size * current / target for every configured target,
+// taking the largest proposal and ignoring deviations within 10% of the target.
+// An eviction rate below target never scales down, as an idle cache has no evictions either; set
+// TargetMemoryUtilizationPercent to also scale down.
+//
+// The operator scales by writing spec.size, which makes it a manager of that field: a server-side apply that sets
+// size conflicts unless it forces ownership, so leave size out of applied configurations. Do not also target the
+// Swxfll with a HorizontalPodAutoscaler through the scale subresource, as both would keep overwriting spec.size.
+// The admission webhook warns about both when autoscaling is enabled.
+type AutoscalingSpec struct {
+	// MinReplicas is the lower bound of Size
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	// +optional
+	MinReplicas int32 `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the upper bound of Size
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// TargetEvictionsPerSecond is the eviction rate summed over all instances above which the cache is scaled up, e.g. 10 or 500m
+	// +optional
+	TargetEvictionsPerSecond *resource.Quantity `json:"targetEvictionsPerSecond,omitempty"`
+
+	// TargetMemoryUtilizationPercent is the share of the memory available for items, summed over all instances,
+	// that the cache should use
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	TargetMemoryUtilizationPercent *int32 `json:"targetMemoryUtilizationPercent,omitempty"`
+
+	// ScaleUpCooldownSeconds is the minimum time between a scaling decision and the next scale up
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=60
+	// +optional
+	ScaleUpCooldownSeconds *int32 `json:"scaleUpCooldownSeconds,omitempty"`
+
+	// ScaleDownCooldownSeconds is the minimum time between a scaling decision and the next scale down.
+	// New instances start empty, so it should be long enough for them to warm up.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=300
+	// +optional
+	ScaleDownCooldownSeconds *int32 `json:"scaleDownCooldownSeconds,omitempty"`
+}
+
+// DisruptionBudgetSpec defines the PodDisruptionBudget of the instances. At most one of MaxUnavailable and
+// MinAvailable may be set; when neither is set MaxUnavailable defaults to a quarter of Size, and at least 1.
+type DisruptionBudgetSpec struct {
+	// MaxUnavailable is the number or percentage of instances that can be unavailable after an eviction
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+
+	// MinAvailable is the number or percentage of instances that must still be available after an eviction
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+}
+
+// ProxyRoute selects how mcrouter routes requests to the instances
+// +kubebuilder:validation:Enum=Sharded;Replicated
+type ProxyRoute string
+
+const (
+	// ProxyRouteSharded spreads the keys over the instances by consistent hashing
+	ProxyRouteSharded ProxyRoute = "Sharded"
+	// ProxyRouteReplicated writes every key to all instances and reads it from one of them
+	ProxyRouteReplicated ProxyRoute = "Replicated"
+)
+
+const (
+	// DefaultProxyPort is the port mcrouter listens on when proxy.port is not set
+	DefaultProxyPort int32 = 5000
+)
+
+// ProxySpec defines the mcrouter Deployment and Service deployed in front of the instances
+type ProxySpec struct {
+	// Enabled deploys the mcrouter Deployment, its config and Service. All of them are removed when it is disabled.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Image of mcrouter. Defaults to the mcrouter image configured on the operator (SWXFLL_MCROUTER_IMAGE).
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Replicas is the number of mcrouter pods
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=2
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Port mcrouter listens on and exposes through its Service
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=5000
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// Route selects how requests are routed to the instances. Sharded (the default) spreads the keys over the
+	// instances; Replicated writes to every instance and reads from one, failing over to the others on errors.
+	// +kubebuilder:default=Sharded
+	// +optional
+	Route ProxyRoute `json:"route,omitempty"`
+
+	// Failover retries a sharded request that failed on its instance on another instance, chosen by rehashing the key
+	// +optional
+	Failover bool `json:"failover,omitempty"`
+
+	// Resources of the mcrouter container
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+const (
+	// GeneratedCertificateDuration is the validity of the server certificates generated by the operator
+	GeneratedCertificateDuration = 90 * 24 * time.Hour
+)
+
+// TLSSpec configures TLS for the client connections to the instances
+type TLSSpec struct {
+	// Enabled starts memcached with TLS. Every connection to the memcached port, including the ones from the
+	// exporter, the proxy and the operator, must then use TLS.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// SecretName references a kubernetes.io/tls Secret in the same namespace holding tls.crt, tls.key and the
+	// ca.crt that issued them. The Secret is reloaded by memcached whenever its certificate changes.
+	// When empty, the operator generates a self-signed CA and a server certificate and renews them before they expire.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// RenewBefore is how long before expiry the operator renews the certificates it generated. A renewed CA is
+	// first published in ca.crt next to the old one, and only signs the server certificate once the old CA is within
+	// renewBefore/2 of its expiry, so clients have renewBefore/2 to trust the new CA.
+	// +kubebuilder:default="720h"
+	// +optional
+	RenewBefore *metav1.Duration `json:"renewBefore,omitempty"`
+}
+
+// AuthSpec configures SASL authentication
+type AuthSpec struct {
+	// Enabled starts memcached with SASL. SASL disables the ASCII protocol, so clients must use the binary protocol;
+	// it cannot be combined with monitoring or the proxy, which only speak the ASCII protocol.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// SecretName references a Secret in the same namespace holding the username and password keys.
+	// When empty, the operator generates a kubernetes.io/basic-auth Secret named <name>-auth; delete it to rotate the
+	// password. Whenever the credentials change the pods are rolled, and the previous credentials stay valid until
+	// the next change so clients can switch over. memcached only checks the first entry of a username, so a change of
+	// the password alone is rejected: the instances keep the previous credentials and the Degraded condition asks to
+	// change the username too.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// SwxfllStatus defines the observed state of Swxfll
+type SwxfllStatus struct {
+	// Conditions store the status conditions of the Memcached instances.
+	// Types are "Available", "Progressing", "Degraded" and "Drifted". Available is only True once every replica
+	// runs the latest pod template and is available, so kubectl wait --for=condition=Available means the cache is serving.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// CurrentVersion is the operand version every replica runs. It only changes once the rollout of a new version completes.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	CurrentVersion string `json:"currentVersion,omitempty"`
+
+	// Replicas is the number of pods currently targeted by the Deployment, reported through the scale subresource
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Selector is the label selector of the pods, in string form, used by HorizontalPodAutoscalers through the scale subresource
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// ReadyReplicas is the number of pods of the Deployment that are ready to serve clients
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// CurrentImage is the operand image every replica runs. Like CurrentVersion it only changes once a rollout completes.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	CurrentImage string `json:"currentImage,omitempty"`
+
+	// Endpoints are the ip:port addresses of the ready pods, sorted
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Endpoints []string `json:"endpoints,omitempty"`
+
+	// ServerList is the ordered server list of the ready pods published for clients building a consistent-hash ring
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	ServerList *ServerListStatus `json:"serverList,omitempty"`
+
+	// Proxy reports the mcrouter tier when spec.proxy is enabled
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Proxy *ProxyStatus `json:"proxy,omitempty"`
+
+	// TLS reports the certificate served by the instances when spec.tls is enabled
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	TLS *TLSStatus `json:"tls,omitempty"`
+
+	// Auth reports the credentials accepted by the instances when spec.auth is enabled
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Auth *AuthStatus `json:"auth,omitempty"`
+
+	// ServiceMonitor is the name of the ServiceMonitor created for the instances when spec.monitoring is enabled and
+	// the Prometheus Operator is installed
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	ServiceMonitor string `json:"serviceMonitor,omitempty"`
+
+	// ObservedGeneration is the generation of the spec the status was computed from
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastReconcileTime is the last time the reconciler converged the owned resources
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	LastReconcileTime *metav1.Time `json:"lastReconcileTime,omitempty"`
+
+	// Stats summarizes the memcached stats of the ready pods, scraped periodically over the memcached text protocol
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Stats *CacheStats `json:"stats,omitempty"`
+
+	// Autoscaling records the scaling decisions taken by the operator when spec.autoscaling is set
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Autoscaling *AutoscalingStatus `json:"autoscaling,omitempty"`
+}
+
+// AutoscalingStatus is the audit trail of the autoscaler
+type AutoscalingStatus struct {
+	// LastScaleTime is the time of the latest scaling decision, from which the cooldowns are measured
+	// +optional
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
+
+	// Decisions are the latest scaling decisions, oldest first. At most 10 are kept.
+	// +optional
+	Decisions []ScalingDecision `json:"decisions,omitempty"`
+}
+
+// ScalingDecision is a change of size made by the autoscaler
+type ScalingDecision struct {
+	// Time the decision was taken
+	Time metav1.Time `json:"time"`
+
+	// FromReplicas is the size before the decision
+	FromReplicas int32 `json:"fromReplicas"`
+
+	// ToReplicas is the size after the decision
+	ToReplicas int32 `json:"toReplicas"`
+
+	// Reason is a CamelCase reason for the decision, e.g. EvictionRateAboveTarget
+	Reason string `json:"reason"`
+
+	// Message explains the decision with the observed and target values
+	Message string `json:"message"`
+}
+
+// ServerListStatus describes the server list published in the ConfigMap
+type ServerListStatus struct {
+	// ConfigMap is the name of the ConfigMap holding the list as a plain list (servers), a JSON array (servers.json)
+	// and an mcrouter pool config (mcrouter.json)
+	ConfigMap string `json:"configMap"`
+
+	// Servers are the host:port addresses of the ready pods, sorted. Pods of a StatefulSet are listed by their stable
+	// DNS name under the headless Service, other pods by their IP.
+	// +optional
+	Servers []string `json:"servers,omitempty"`
+
+	// Hash is the SHA-256 of the plain list. It changes whenever the membership changes.
+	Hash string `json:"hash"`
+}
+
+// ProxyStatus reports the mcrouter Deployment
+type ProxyStatus struct {
+	// Endpoint is the host:port of the mcrouter Service clients should connect to
+	Endpoint string `json:"endpoint"`
+
+	// ReadyReplicas is the number of ready mcrouter pods
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// ConfigHash is the SHA-256 of the rendered mcrouter config. The proxy pods are rolled whenever it changes.
+	ConfigHash string `json:"configHash"`
+}
+
+// TLSStatus reports the certificate served by the instances
+type TLSStatus struct {
+	// SecretName is the Secret holding the certificate, generated by the operator unless spec.tls.secretName is set
+	SecretName string `json:"secretName"`
+
+	// ServerName is the DNS name clients should verify the certificate against, including when they connect to the
+	// pods by IP
+	ServerName string `json:"serverName"`
+
+	// SerialNumber of the certificate, in hexadecimal
+	SerialNumber string `json:"serialNumber"`
+
+	// NotAfter is when the certificate expires
+	NotAfter metav1.Time `json:"notAfter"`
+
+	// CANotAfter is when the CA generated by the operator expires
+	// +optional
+	CANotAfter *metav1.Time `json:"caNotAfter,omitempty"`
+}
+
+// AuthStatus reports the credentials accepted by the instances
+type AuthStatus struct {
+	// SecretName is the Secret holding the current credentials
+	SecretName string `json:"secretName"`
+
+	// PreviousUsername is the username of the previous credentials, which are still accepted
+	// +optional
+	PreviousUsername string `json:"previousUsername,omitempty"`
+
+	// LastRotationTime is when the credentials last changed
+	// +optional
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
+}
+
+// CacheStats is the sum of the memcached stats of the pods they were scraped from
+type CacheStats struct {
+	// Pods is the number of pods the stats were scraped from
+	Pods int32 `json:"pods"`
+
+	// HitRatio is getHits / (getHits + getMisses) as a decimal string, e.g. "0.9732". Empty before the first get.
+	// +optional
+	HitRatio string `json:"hitRatio,omitempty"`
+
+	// GetHits is the number of get requests that found an item
+	GetHits int64 `json:"getHits"`
+
+	// GetMisses is the number of get requests that did not find an item
+	GetMisses int64 `json:"getMisses"`
+
+	// Evictions is the number of valid items removed from the cache to free memory
+	Evictions int64 `json:"evictions"`
+
+	// EvictionsPerSecond is the eviction rate between the previous scrape and this one as a decimal string, e.g. "12.5".
+	// Empty on the first scrape.
+	// +optional
+	EvictionsPerSecond string `json:"evictionsPerSecond,omitempty"`
+
+	// CurrentItems is the number of items currently stored
+	CurrentItems int64 `json:"currentItems"`
+
+	// BytesUsed is the number of bytes currently used to store items
+	BytesUsed int64 `json:"bytesUsed"`
+
+	// LimitBytes is the number of bytes the pods may use to store items
+	LimitBytes int64 `json:"limitBytes"`
+
+	// CurrentConnections is the number of open client connections
+	CurrentConnections int64 `json:"currentConnections"`
+
+	// MaxConnections is the maximum number of simultaneous client connections
+	MaxConnections int64 `json:"maxConnections"`
+
+	// LastScrapeTime is the time the stats were scraped
+	LastScrapeTime metav1.Time `json:"lastScrapeTime"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:subresource:scale:specpath=.spec.size,statuspath=.status.replicas,selectorpath=.status.selector
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Size",type=integer,JSONPath=`.spec.size`
+//+kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+//+kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.currentVersion`
+//+kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+//+kubebuilder:printcolumn:name="Workload",type=string,JSONPath=`.spec.workloadType`,priority=1
+//+kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.status.currentImage`,priority=1
+//+kubebuilder:printcolumn:name="Endpoints",type=string,JSONPath=`.status.endpoints`,priority=1
+//+kubebuilder:printcolumn:name="Cert Expiry",type=date,JSONPath=`.status.tls.notAfter`,priority=1
+//+kubebuilder:printcolumn:name="Last Reconcile",type=date,JSONPath=`.status.lastReconcileTime`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Swxfll is the Schema for the swxflls API
+type Swxfll struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SwxfllSpec   `json:"spec,omitempty"`
+	Status SwxfllStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SwxfllList contains a list of Swxfll
+type SwxfllList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Swxfll `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Swxfll{}, &SwxfllList{})
+}
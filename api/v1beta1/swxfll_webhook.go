@@ -0,0 +1,320 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var swxflllog = logf.Log.WithName("swxfll-resource")
+
+// DefaultVersion 是未设置 image 和 version 的 Swxfll 在创建时填入的 operand 版本，
+// 由 manager 在启动时根据默认镜像（SWXFLL_IMAGE）设置，为空时不填充。
+var DefaultVersion string
+
+// SetupWebhookWithManager will setup the manager to manage the webhooks
+func (r *Swxfll) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-cache-swxfll-com-v1beta1-swxfll,mutating=true,failurePolicy=fail,sideEffects=None,groups=cache.swxfll.com,resources=swxflls,verbs=create;update,versions=v1beta1,name=mswxfll.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &Swxfll{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type.
+// 填充默认值后，存储的对象与实际部署的内容保持一致。
+// matchPolicy 默认为 Equivalent，v1alpha1 的请求会先转换为 v1beta1 再交给这里处理。
+func (r *Swxfll) Default() {
+	swxflllog.Info("default", "name", r.Name)
+
+	if r.Spec.Size == 0 {
+		r.Spec.Size = 1
+	}
+	if r.Spec.WorkloadType == "" {
+		r.Spec.WorkloadType = WorkloadTypeDeployment
+	}
+	if r.Spec.Networking == nil {
+		r.Spec.Networking = &NetworkingSpec{}
+	}
+	if r.Spec.Networking.Port == 0 {
+		r.Spec.Networking.Port = DefaultContainerPort
+	}
+	if r.Spec.Memcached == nil {
+		r.Spec.Memcached = &MemcachedSpec{}
+	}
+	if r.Spec.Memcached.MemoryMB == 0 {
+		r.Spec.Memcached.MemoryMB = defaultMemoryMB
+	}
+	if r.Spec.Image == "" && r.Spec.Version == "" {
+		r.Spec.Version = DefaultVersion
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-cache-swxfll-com-v1beta1-swxfll,mutating=false,failurePolicy=fail,sideEffects=None,groups=cache.swxfll.com,resources=swxflls,verbs=create;update,versions=v1beta1,name=vswxfll.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &Swxfll{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Swxfll) ValidateCreate() (admission.Warnings, error) {
+	swxflllog.Info("validate create", "name", r.Name)
+
+	return r.autoscalingWarnings(nil), r.validateSwxfll(nil)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Swxfll) ValidateUpdate(old runtime.Object) (admission.Warnings, error) {
+	swxflllog.Info("validate update", "name", r.Name)
+
+	oldSwxfll, ok := old.(*Swxfll)
+	if !ok {
+		return nil, fmt.Errorf("expected a Swxfll but got a %T", old)
+	}
+	return r.autoscalingWarnings(oldSwxfll), r.validateSwxfll(oldSwxfll)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Swxfll) ValidateDelete() (admission.Warnings, error) {
+	swxflllog.Info("validate delete", "name", r.Name)
+
+	return nil, nil
+}
+
+// autoscalingWarnings 在开启自动扩缩容时提醒用户 operator 会修改 spec.size，
+// 已经开启的更新不再重复提醒，operator 自己扩缩容时也不会收到警告。
+func (r *Swxfll) autoscalingWarnings(old *Swxfll) admission.Warnings {
+	if r.Spec.Autoscaling == nil || (old != nil && old.Spec.Autoscaling != nil) {
+		return nil
+	}
+	return admission.Warnings{
+		"spec.autoscaling: the operator writes spec.size, so a server-side apply setting size conflicts unless it " +
+			"forces ownership; leave size out of applied configurations",
+		"spec.autoscaling: do not also scale this Swxfll with a HorizontalPodAutoscaler, both would overwrite spec.size",
+	}
+}
+
+// validateSwxfll 校验 spec，old 不为空时还会拒绝对不可变字段的修改。
+// 更新没有修改 spec 或者对象正在删除时不校验 spec，
+// 在后来加入的规则之前存储的对象仍然可以添加和移除 finalizer，不会无法删除。
+func (r *Swxfll) validateSwxfll(old *Swxfll) error {
+	var allErrs field.ErrorList
+	if old == nil || (r.DeletionTimestamp == nil && !equality.Semantic.DeepEqual(r.Spec, old.Spec)) {
+		allErrs = r.validateSpec()
+	}
+	if old != nil {
+		allErrs = append(allErrs, r.validateImmutableFields(old)...)
+	}
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("Swxfll").GroupKind(), r.Name, allErrs)
+}
+
+func (r *Swxfll) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if r.Spec.Size < 1 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("size"), r.Spec.Size, "must be at least 1"))
+	}
+
+	networkingPath := specPath.Child("networking")
+	var networking NetworkingSpec
+	if r.Spec.Networking != nil {
+		networking = *r.Spec.Networking
+	}
+	portPath := networkingPath.Child("port")
+	if networking.Port < 1 || networking.Port > 65535 {
+		allErrs = append(allErrs, field.Invalid(portPath, networking.Port, "must be between 1 and 65535"))
+	} else if networking.Port == MetricsPort {
+		allErrs = append(allErrs, field.Invalid(portPath, networking.Port,
+			fmt.Sprintf("collides with the port %d reserved for metrics", MetricsPort)))
+	}
+
+	if svc := networking.Service; svc != nil {
+		svcPath := networkingPath.Child("service")
+		if svc.Port == MetricsPort {
+			allErrs = append(allErrs, field.Invalid(svcPath.Child("port"), svc.Port,
+				fmt.Sprintf("collides with the port %d reserved for metrics", MetricsPort)))
+		}
+		if svc.NodePort != 0 && (svc.Type == "" || svc.Type == corev1.ServiceTypeClusterIP) {
+			allErrs = append(allErrs, field.Forbidden(svcPath.Child("nodePort"),
+				"may only be set when type is NodePort or LoadBalancer"))
+		}
+	}
+
+	memoryMB := int64(defaultMemoryMB)
+	if mc := r.Spec.Memcached; mc != nil {
+		if mc.MemoryMB != 0 {
+			memoryMB = int64(mc.MemoryMB)
+		}
+		if mc.MaxItemSize != nil {
+			sizePath := specPath.Child("memcached", "maxItemSize")
+			size := mc.MaxItemSize.Value()
+			if size < 1<<10 || size > 1<<30 {
+				allErrs = append(allErrs, field.Invalid(sizePath, mc.MaxItemSize.String(), "must be between 1Ki and 1Gi"))
+			} else if size > memoryMB<<20/2 {
+				allErrs = append(allErrs, field.Invalid(sizePath, mc.MaxItemSize.String(),
+					fmt.Sprintf("must not exceed half of memcached.memoryMB (%dMB)", memoryMB)))
+			}
+		}
+	}
+
+	// Manual 模式下内存 limit 必须能容纳 memcached 配置的内存，否则缓存写满后会被 OOM kill
+	if res := r.resources(); res != nil && res.Mode == ResourcesModeManual {
+		if limit, ok := res.Limits[corev1.ResourceMemory]; ok && limit.Value() <= memoryMB<<20 {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("scheduling", "resources", "limits", "memory"), limit.String(),
+				fmt.Sprintf("must be larger than memcached.memoryMB (%dMB)", memoryMB)))
+		}
+	}
+
+	if as := r.Spec.Autoscaling; as != nil {
+		asPath := specPath.Child("autoscaling")
+		if as.MinReplicas > as.MaxReplicas {
+			allErrs = append(allErrs, field.Invalid(asPath.Child("minReplicas"), as.MinReplicas,
+				fmt.Sprintf("must not be greater than maxReplicas (%d)", as.MaxReplicas)))
+		}
+		if as.TargetEvictionsPerSecond == nil && as.TargetMemoryUtilizationPercent == nil {
+			allErrs = append(allErrs, field.Required(asPath,
+				"at least one of targetEvictionsPerSecond and targetMemoryUtilizationPercent must be set"))
+		}
+		if as.TargetEvictionsPerSecond != nil && as.TargetEvictionsPerSecond.Sign() <= 0 {
+			allErrs = append(allErrs, field.Invalid(asPath.Child("targetEvictionsPerSecond"),
+				as.TargetEvictionsPerSecond.String(), "must be greater than 0"))
+		}
+	}
+
+	// SASL 会关闭 ASCII 协议，而 exporter 和 mcrouter 只支持 ASCII 协议
+	if r.Spec.Auth != nil && r.Spec.Auth.Enabled {
+		if r.Spec.Monitoring != nil && r.Spec.Monitoring.Enabled {
+			allErrs = append(allErrs, field.Forbidden(specPath.Child("monitoring", "enabled"),
+				"the exporter does not support SASL; use the metrics of the operator instead"))
+		}
+		if r.Spec.Proxy != nil && r.Spec.Proxy.Enabled {
+			allErrs = append(allErrs, field.Forbidden(specPath.Child("proxy", "enabled"),
+				"mcrouter does not support SASL"))
+		}
+	}
+
+	if t := r.Spec.TLS; t != nil && t.RenewBefore != nil {
+		renewBefore := t.RenewBefore.Duration
+		if renewBefore <= 0 || renewBefore >= GeneratedCertificateDuration {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("tls", "renewBefore"), t.RenewBefore.String(),
+				fmt.Sprintf("must be greater than 0 and less than the certificate duration (%s)", GeneratedCertificateDuration)))
+		}
+	}
+
+	if db := r.Spec.DisruptionBudget; db != nil {
+		dbPath := specPath.Child("disruptionBudget")
+		if db.MaxUnavailable != nil && db.MinAvailable != nil {
+			allErrs = append(allErrs, field.Forbidden(dbPath.Child("minAvailable"),
+				"may not be set together with maxUnavailable"))
+		}
+		allErrs = append(allErrs, validateIntOrPercent(db.MaxUnavailable, dbPath.Child("maxUnavailable"))...)
+		allErrs = append(allErrs, validateIntOrPercent(db.MinAvailable, dbPath.Child("minAvailable"))...)
+	}
+
+	allErrs = append(allErrs, validateNetworkPeers(r.Spec.AllowedClients, specPath.Child("allowedClients"))...)
+	if r.Spec.Monitoring != nil {
+		allErrs = append(allErrs, validateNetworkPeers(r.Spec.Monitoring.AllowedScrapers,
+			specPath.Child("monitoring", "allowedScrapers"))...)
+	}
+
+	return allErrs
+}
+
+// validateIntOrPercent 校验 value 是非负整数或 0% 到 100% 之间的百分比
+func validateIntOrPercent(value *intstr.IntOrString, fldPath *field.Path) field.ErrorList {
+	if value == nil {
+		return nil
+	}
+	if value.Type == intstr.Int {
+		if value.IntVal < 0 {
+			return field.ErrorList{field.Invalid(fldPath, value.String(), "must be greater than or equal to 0")}
+		}
+		return nil
+	}
+	percent, err := intstr.GetScaledValueFromIntOrPercent(value, 100, false)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, value.String(), "must be an integer or a percentage, e.g. 25%")}
+	}
+	if percent < 0 || percent > 100 {
+		return field.ErrorList{field.Invalid(fldPath, value.String(), "must be between 0% and 100%")}
+	}
+	return nil
+}
+
+// validateNetworkPeers 校验 NetworkPeer 中的 label selector，无效的 selector 会让 NetworkPolicy 创建失败
+func validateNetworkPeers(peers []NetworkPeer, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	opts := metav1validation.LabelSelectorValidationOptions{}
+	for i, peer := range peers {
+		peerPath := fldPath.Index(i)
+		if peer.NamespaceSelector != nil {
+			allErrs = append(allErrs, metav1validation.ValidateLabelSelector(
+				peer.NamespaceSelector, opts, peerPath.Child("namespaceSelector"))...)
+		}
+		if peer.PodSelector != nil {
+			allErrs = append(allErrs, metav1validation.ValidateLabelSelector(
+				peer.PodSelector, opts, peerPath.Child("podSelector"))...)
+		}
+	}
+	return allErrs
+}
+
+// validateImmutableFields 拒绝对创建后不允许修改的字段的更新。
+// networking.port 被客户端配置和一致性哈希环引用，修改它会让所有客户端同时失效。
+func (r *Swxfll) validateImmutableFields(old *Swxfll) field.ErrorList {
+	var allErrs field.ErrorList
+	if oldPort := old.port(); oldPort != 0 {
+		allErrs = append(allErrs, apivalidation.ValidateImmutableField(
+			r.port(), oldPort, field.NewPath("spec", "networking", "port"))...)
+	}
+	return allErrs
+}
+
+// port 返回 networking.port，未设置时返回 0
+func (r *Swxfll) port() int32 {
+	if r.Spec.Networking == nil {
+		return 0
+	}
+	return r.Spec.Networking.Port
+}
+
+// resources 返回 scheduling.resources，未设置时返回 nil
+func (r *Swxfll) resources() *ResourcesSpec {
+	if r.Spec.Scheduling == nil {
+		return nil
+	}
+	return r.Spec.Scheduling.Resources
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var _ = Describe("Swxfll Webhook", func() {
+	newSwxfll := func(name string) *Swxfll {
+		return &Swxfll{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: SwxfllSpec{
+				Size:       1,
+				Networking: &NetworkingSpec{Port: 11211},
+			},
+		}
+	}
+
+	Context("When creating Swxfll under Defaulting Webhook", func() {
+		It("Should fill in the defaults of an empty spec", func() {
+			swxfll := &Swxfll{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "defaults",
+					Namespace: "default",
+				},
+			}
+			Expect(k8sClient.Create(ctx, swxfll)).To(Succeed())
+
+			Expect(swxfll.Spec.Size).To(Equal(int32(1)))
+			Expect(swxfll.Spec.WorkloadType).To(Equal(WorkloadTypeDeployment))
+			Expect(swxfll.Spec.Networking).NotTo(BeNil())
+			Expect(swxfll.Spec.Networking.Port).To(Equal(DefaultContainerPort))
+			Expect(swxfll.Spec.Memcached).NotTo(BeNil())
+			Expect(swxfll.Spec.Memcached.MemoryMB).To(Equal(int32(64)))
+			Expect(swxfll.Spec.Version).To(Equal(DefaultVersion))
+
+			Expect(k8sClient.Delete(ctx, swxfll)).To(Succeed())
+		})
+
+		It("Should not pin a version when a custom image is set", func() {
+			swxfll := newSwxfll("custom-image")
+			swxfll.Spec.Image = "registry.example.com/memcached:1.6.23"
+			Expect(k8sClient.Create(ctx, swxfll)).To(Succeed())
+
+			Expect(swxfll.Spec.Version).To(BeEmpty())
+
+			Expect(k8sClient.Delete(ctx, swxfll)).To(Succeed())
+		})
+	})
+
+	Context("When creating Swxfll under Validating Webhook", func() {
+		It("Should admit a valid spec", func() {
+			swxfll := newSwxfll("valid")
+			Expect(k8sClient.Create(ctx, swxfll)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, swxfll)).To(Succeed())
+		})
+
+		It("Should deny a port out of range", func() {
+			swxfll := newSwxfll("port-range")
+			swxfll.Spec.Networking.Port = 70000
+			err := k8sClient.Create(ctx, swxfll)
+			Expect(err).To(MatchError(ContainSubstring("spec.networking.port: Invalid value: 70000")))
+		})
+
+		It("Should deny a port colliding with the metrics port", func() {
+			swxfll := newSwxfll("port-metrics")
+			swxfll.Spec.Networking.Port = MetricsPort
+			err := k8sClient.Create(ctx, swxfll)
+			Expect(err).To(MatchError(ContainSubstring("reserved for metrics")))
+		})
+
+		It("Should deny an item size larger than half of the memory", func() {
+			swxfll := newSwxfll("item-size")
+			swxfll.Spec.Memcached = &MemcachedSpec{MemoryMB: 32, MaxItemSize: ptrTo(resource.MustParse("32Mi"))}
+			err := k8sClient.Create(ctx, swxfll)
+			Expect(err).To(MatchError(ContainSubstring("spec.memcached.maxItemSize")))
+		})
+
+		It("Should deny a manual memory limit that cannot hold the cache", func() {
+			swxfll := newSwxfll("memory-limit")
+			swxfll.Spec.Memcached = &MemcachedSpec{MemoryMB: 512}
+			swxfll.Spec.Scheduling = &SchedulingSpec{
+				Resources: &ResourcesSpec{
+					Mode:   ResourcesModeManual,
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+				},
+			}
+			err := k8sClient.Create(ctx, swxfll)
+			Expect(err).To(MatchError(ContainSubstring("spec.scheduling.resources.limits.memory")))
+		})
+
+		It("Should deny a nodePort on a ClusterIP Service", func() {
+			swxfll := newSwxfll("node-port")
+			swxfll.Spec.Networking.Service = &ServiceSpec{NodePort: 30000}
+			err := k8sClient.Create(ctx, swxfll)
+			Expect(err).To(MatchError(ContainSubstring("spec.networking.service.nodePort")))
+		})
+
+		It("Should deny an autoscaling policy without bounds or targets", func() {
+			swxfll := newSwxfll("autoscaling")
+			swxfll.Spec.Autoscaling = &AutoscalingSpec{MinReplicas: 3, MaxReplicas: 2}
+			err := k8sClient.Create(ctx, swxfll)
+			Expect(err).To(MatchError(ContainSubstring("spec.autoscaling.minReplicas")))
+			Expect(err).To(MatchError(ContainSubstring("targetMemoryUtilizationPercent must be set")))
+		})
+
+		It("Should deny renewing certificates before they are issued", func() {
+			swxfll := newSwxfll("tls")
+			swxfll.Spec.TLS = &TLSSpec{Enabled: true, RenewBefore: &metav1.Duration{Duration: GeneratedCertificateDuration}}
+			err := k8sClient.Create(ctx, swxfll)
+			Expect(err).To(MatchError(ContainSubstring("spec.tls.renewBefore")))
+		})
+
+		It("Should deny SASL together with the exporter or the proxy", func() {
+			swxfll := newSwxfll("auth")
+			swxfll.Spec.Auth = &AuthSpec{Enabled: true}
+			swxfll.Spec.Monitoring = &MonitoringSpec{Enabled: true}
+			swxfll.Spec.Proxy = &ProxySpec{Enabled: true}
+			err := k8sClient.Create(ctx, swxfll)
+			Expect(err).To(MatchError(ContainSubstring("spec.monitoring.enabled")))
+			Expect(err).To(MatchError(ContainSubstring("spec.proxy.enabled")))
+		})
+
+		It("Should deny conflicting or malformed disruption budgets", func() {
+			swxfll := newSwxfll("disruption")
+			swxfll.Spec.DisruptionBudget = &DisruptionBudgetSpec{
+				MaxUnavailable: ptrTo(intstr.FromString("quarter")),
+				MinAvailable:   ptrTo(intstr.FromInt(2)),
+			}
+			err := k8sClient.Create(ctx, swxfll)
+			Expect(err).To(MatchError(ContainSubstring("spec.disruptionBudget.minAvailable")))
+			Expect(err).To(MatchError(ContainSubstring("spec.disruptionBudget.maxUnavailable")))
+		})
+
+		It("Should deny an invalid selector of the allowed clients", func() {
+			swxfll := newSwxfll("clients")
+			swxfll.Spec.AllowedClients = []NetworkPeer{{
+				PodSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "app", Operator: "Is", Values: []string{"web"}},
+				}},
+			}}
+			err := k8sClient.Create(ctx, swxfll)
+			Expect(err).To(MatchError(ContainSubstring("spec.allowedClients[0].podSelector")))
+		})
+	})
+
+	Context("When updating Swxfll under Validating Webhook", func() {
+		It("Should deny changing the container port", func() {
+			swxfll := newSwxfll("immutable-port")
+			Expect(k8sClient.Create(ctx, swxfll)).To(Succeed())
+
+			swxfll.Spec.Networking.Port = 11311
+			err := k8sClient.Update(ctx, swxfll)
+			Expect(err).To(MatchError(ContainSubstring("field is immutable")))
+
+			Expect(k8sClient.Delete(ctx, swxfll)).To(Succeed())
+		})
+
+		It("Should warn that autoscaling takes over spec.size when it is enabled", func() {
+			swxfll := newSwxfll("autoscaling")
+			warnings, err := swxfll.ValidateUpdate(swxfll.DeepCopy())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+
+			autoscaled := swxfll.DeepCopy()
+			autoscaled.Spec.Autoscaling = &AutoscalingSpec{MinReplicas: 1, MaxReplicas: 3,
+				TargetMemoryUtilizationPercent: ptrTo(int32(80))}
+			warnings, err = autoscaled.ValidateUpdate(swxfll)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ConsistOf(ContainSubstring("server-side apply"), ContainSubstring("HorizontalPodAutoscaler")))
+			Expect(autoscaled.ValidateCreate()).To(HaveLen(2))
+
+			By("not warning again when the operator scales it")
+			scaled := autoscaled.DeepCopy()
+			scaled.Spec.Size = 2
+			Expect(scaled.ValidateUpdate(autoscaled)).To(BeEmpty())
+		})
+
+		It("Should let a Swxfll stored before a later rule remove its finalizer", func() {
+			old := newSwxfll("stored-before-rule")
+			old.Finalizers = []string{"cache.swxfll.com/finalizer"}
+			old.Spec.Auth = &AuthSpec{Enabled: true}
+			old.Spec.Monitoring = &MonitoringSpec{Enabled: true}
+			Expect(old.ValidateCreate()).Error().To(HaveOccurred())
+
+			By("adding a label without changing the spec")
+			labeled := old.DeepCopy()
+			labeled.Labels = map[string]string{"team": "cache"}
+			Expect(labeled.ValidateUpdate(old)).Error().NotTo(HaveOccurred())
+
+			By("removing the finalizer while it is being deleted")
+			deleting := old.DeepCopy()
+			deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			finalized := deleting.DeepCopy()
+			finalized.Finalizers = nil
+			Expect(finalized.ValidateUpdate(deleting)).Error().NotTo(HaveOccurred())
+
+			By("still validating a changed spec and the immutable fields")
+			changed := old.DeepCopy()
+			changed.Spec.Size = 2
+			Expect(changed.ValidateUpdate(old)).Error().To(MatchError(ContainSubstring("spec.monitoring.enabled")))
+			finalized.Spec.Networking.Port = 11311
+			Expect(finalized.ValidateUpdate(deleting)).Error().To(MatchError(ContainSubstring("field is immutable")))
+		})
+	})
+})
@@ -20,21 +20,25 @@ import (
 	"crypto/tls"
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	cachev1alpha1 "github.com/swxfll/operator-sdk-demo/api/v1alpha1"
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
 	"github.com/swxfll/operator-sdk-demo/internal/controller"
 	//+kubebuilder:scaffold:imports
 )
@@ -59,6 +63,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	// 用于插入自定义资源的 Scheme 相关的代码。
 	utilruntime.Must(cachev1alpha1.AddToScheme(scheme))
+	utilruntime.Must(cachev1beta1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -70,6 +75,7 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var statsInterval time.Duration
 	// 解析命令行参数，并根据这些参数配置日志记录器
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -80,6 +86,8 @@ func main() {
 		"If set the metrics endpoint is served securely")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.DurationVar(&statsInterval, "stats-interval", 30*time.Second,
+		"How often the memcached stats of every Swxfll are scraped into its status. 0 disables scraping.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -131,6 +139,13 @@ func main() {
 			SecureServing: secureMetrics,
 			TLSOpts:       tlsOpts,
 		},
+		// Secret 不经过缓存，直接从 API Server 读取。控制器只通过元数据 watch Secret，
+		// 缓存完整的 Secret 会在内存中保存集群中所有 Secret 的内容
+		Client: client.Options{
+			Cache: &client.CacheOptions{
+				DisableFor: []client.Object{&corev1.Secret{}},
+			},
+		},
 		// 用于指定 webhook 服务器的实例，即 webhookServer。
 		WebhookServer: webhookServer,
 		//HealthProbeBindAddress：用于指定健康探针绑定地址。
@@ -162,10 +177,25 @@ func main() {
 		Scheme: mgr.GetScheme(),
 		//此记录器将在控制器的协调方法中使用以发出事件。
 		Recorder: mgr.GetEventRecorderFor("swxfll-controller"),
+		// 定期通过 memcached 文本协议采集每个 Pod 的统计信息
+		StatsInterval: statsInterval,
+		// 由 config/manager/manager.yaml 通过 downward API 注入，NetworkPolicy 通过它允许 operator 连接 memcached
+		OperatorNamespace: os.Getenv("POD_NAMESPACE"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Swxfll")
 		os.Exit(1)
 	}
+	// 本地运行（make run）时没有 webhook 证书，可以通过 ENABLE_WEBHOOKS=false 关闭 webhook
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		// 创建时未指定 image 和 version 的 Swxfll 会被填入默认镜像的版本
+		cachev1beta1.DefaultVersion = controller.DefaultVersion()
+		// 准入 webhook 注册在存储版本 v1beta1 上，v1alpha1 的请求会先转换再处理；
+		// 由于 scheme 中注册了可转换的 v1alpha1，这里还会同时注册 /convert 转换 webhook
+		if err = (&cachev1beta1.Swxfll{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Swxfll")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	// 添加健康探针（Healthz Check）
@@ -0,0 +1,267 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+	"github.com/swxfll/operator-sdk-demo/internal/memcached"
+)
+
+const (
+	// saslVolumeName 是挂载 SASL 配置的卷名
+	saslVolumeName = "sasl"
+	// saslMountPath 是 memcached 容器中 SASL 配置的挂载目录，通过 SASL_CONF_PATH 传给 memcached
+	saslMountPath = "/etc/memcached/sasl"
+	// saslConfigKey 是 SASL 配置文件的键，cyrus-sasl 在 SASL_CONF_PATH 中查找以应用名命名的配置文件
+	saslConfigKey = "memcached.conf"
+	// saslPasswordDBKey 是 user:password 格式的密码文件的键，通过 MEMCACHED_SASL_PWDB 传给 memcached
+	saslPasswordDBKey = "pwdb"
+	// credentialsRotatedAnnotation 记录凭据最后一次变化的时间，凭据变化时工作负载会滚动更新以加载新的密码文件
+	credentialsRotatedAnnotation = "cache.swxfll.com/credentials-rotated"
+	// passwordOnlyRotationReason 是凭据只修改了密码而被拒绝时 Degraded 条件和 Event 的原因
+	passwordOnlyRotationReason = "PasswordOnlyRotation"
+)
+
+// authEnabled 返回是否为 swxfll 开启了 SASL 认证
+func authEnabled(swxfll *cachev1beta1.Swxfll) bool {
+	return swxfll.Spec.Auth != nil && swxfll.Spec.Auth.Enabled
+}
+
+// authSecretNameForSwxfll 返回保存当前凭据的 Secret 名称，未引用用户的 Secret 时使用 operator 生成的 Secret
+func authSecretNameForSwxfll(swxfll *cachev1beta1.Swxfll) string {
+	if name := swxfll.Spec.Auth.SecretName; name != "" {
+		return name
+	}
+	return swxfll.Name + "-auth"
+}
+
+// saslSecretName 返回保存 SASL 配置和密码文件的 Secret 名称
+func saslSecretName(name string) string {
+	return name + "-sasl"
+}
+
+// authArgs 返回开启 SASL 的 memcached 参数
+func authArgs() []string {
+	return []string{"-S"}
+}
+
+// authEnvForSwxfll 返回 memcached 读取 SASL 配置和密码文件需要的环境变量
+func authEnvForSwxfll() []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{Name: "SASL_CONF_PATH", Value: saslMountPath},
+		{Name: "MEMCACHED_SASL_PWDB", Value: saslMountPath + "/" + saslPasswordDBKey},
+	}
+}
+
+// authVolumeForSwxfll 返回挂载 SASL 配置的卷
+func authVolumeForSwxfll(swxfll *cachev1beta1.Swxfll) corev1.Volume {
+	return corev1.Volume{Name: saslVolumeName, VolumeSource: corev1.VolumeSource{
+		Secret: &corev1.SecretVolumeSource{SecretName: saslSecretName(swxfll.Name)},
+	}}
+}
+
+// reconcileAuth 在开启认证时读取或生成凭据，并渲染 memcached 使用的密码文件，返回 operator 采集统计信息时使用的凭据。
+// 凭据变化后密码文件中同时保留上一个凭据，滚动更新期间以及之后客户端都可以逐步切换到新的凭据，
+// 上一个凭据在下一次变化时才会失效。memcached 只检查密码文件中第一个同名用户，
+// 因此只修改密码的凭据会被拒绝：继续使用密码文件中的凭据，并通过 Degraded 条件和 Event 提示用户同时修改用户名。
+// operator 生成的凭据每次都使用新的用户名。
+func (r *SwxfllReconciler) reconcileAuth(ctx context.Context, swxfll *cachev1beta1.Swxfll, now time.Time) (
+	*memcached.Credentials, error) {
+	if cond := meta.FindStatusCondition(swxfll.Status.Conditions, typeDegradedSwxfll); cond != nil &&
+		cond.Reason == passwordOnlyRotationReason {
+		meta.RemoveStatusCondition(&swxfll.Status.Conditions, typeDegradedSwxfll)
+	}
+	if !authEnabled(swxfll) {
+		swxfll.Status.Auth = nil
+		return nil, nil
+	}
+
+	credentials, err := r.credentialsForSwxfll(ctx, swxfll)
+	if err != nil {
+		return nil, err
+	}
+
+	sasl, err := r.getSecret(ctx, swxfll.Namespace, saslSecretName(swxfll.Name))
+	if err != nil {
+		return nil, err
+	}
+	current := credentials.Username + ":" + credentials.Password
+	entries := passwordDBEntries(sasl)
+	if len(entries) > 0 && entries[0] != current && usernameOf(entries[0]) == credentials.Username {
+		message := fmt.Sprintf("The password in Secret %s changed but the username did not; the previous password "+
+			"stays in use until the username changes too, so that both credentials are valid while clients switch over",
+			authSecretNameForSwxfll(swxfll))
+		meta.SetStatusCondition(&swxfll.Status.Conditions, metav1.Condition{Type: typeDegradedSwxfll,
+			Status: metav1.ConditionTrue, Reason: passwordOnlyRotationReason, Message: message})
+		r.Recorder.Event(swxfll, "Warning", passwordOnlyRotationReason, message)
+		credentials = &memcached.Credentials{
+			Username: credentials.Username,
+			Password: strings.TrimPrefix(entries[0], credentials.Username+":"),
+		}
+	} else if len(entries) == 0 || entries[0] != current {
+		pwdb := []string{current}
+		if len(entries) > 0 {
+			pwdb = append(pwdb, entries[0])
+		}
+		sasl, err = r.saslSecretForSwxfll(swxfll, strings.Join(pwdb, "\n")+"\n", now)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.apply(ctx, sasl); err != nil {
+			return nil, err
+		}
+		log.FromContext(ctx).Info("凭据已更新", "Secret.Name", sasl.Name, "username", credentials.Username)
+	}
+
+	// 状态总是从密码文件推导，后续步骤失败时下一次调和也会得到相同的结果
+	status := &cachev1beta1.AuthStatus{SecretName: authSecretNameForSwxfll(swxfll)}
+	if entries := passwordDBEntries(sasl); len(entries) > 1 {
+		status.PreviousUsername = usernameOf(entries[1])
+	}
+	if rotated, err := time.Parse(time.RFC3339, sasl.Annotations[credentialsRotatedAnnotation]); err == nil {
+		status.LastRotationTime = &metav1.Time{Time: rotated}
+	}
+	swxfll.Status.Auth = status
+	return credentials, nil
+}
+
+// passwordDBEntries 返回 SASL Secret 中密码文件的每一行，第一行是当前的凭据
+func passwordDBEntries(sasl *corev1.Secret) []string {
+	if sasl == nil {
+		return nil
+	}
+	pwdb := strings.TrimSpace(string(sasl.Data[saslPasswordDBKey]))
+	if pwdb == "" {
+		return nil
+	}
+	return strings.Split(pwdb, "\n")
+}
+
+// usernameOf 返回密码文件中一行 user:password 的用户名
+func usernameOf(entry string) string {
+	return strings.SplitN(entry, ":", 2)[0]
+}
+
+// credentialsForSwxfll 读取用户的凭据 Secret，或者读取 operator 生成的凭据 Secret，不存在时生成新的凭据
+func (r *SwxfllReconciler) credentialsForSwxfll(ctx context.Context, swxfll *cachev1beta1.Swxfll) (
+	*memcached.Credentials, error) {
+	name := authSecretNameForSwxfll(swxfll)
+	secret, err := r.getSecret(ctx, swxfll.Namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		if swxfll.Spec.Auth.SecretName != "" {
+			return nil, fmt.Errorf("credentials Secret %s not found", name)
+		}
+		if secret, err = r.generatedCredentialsSecretForSwxfll(swxfll); err != nil {
+			return nil, err
+		}
+		if err := r.apply(ctx, secret); err != nil {
+			return nil, err
+		}
+		log.FromContext(ctx).Info("生成新的凭据", "Secret.Name", name)
+	}
+
+	credentials := &memcached.Credentials{
+		Username: string(secret.Data[corev1.BasicAuthUsernameKey]),
+		Password: string(secret.Data[corev1.BasicAuthPasswordKey]),
+	}
+	if credentials.Username == "" || strings.ContainsAny(credentials.Username, ":\r\n") {
+		return nil, fmt.Errorf("credentials Secret %s: %s must be set and must not contain ':' or line breaks",
+			name, corev1.BasicAuthUsernameKey)
+	}
+	if credentials.Password == "" || strings.ContainsAny(credentials.Password, "\r\n") {
+		return nil, fmt.Errorf("credentials Secret %s: %s must be set and must not contain line breaks",
+			name, corev1.BasicAuthPasswordKey)
+	}
+	return credentials, nil
+}
+
+// generatedCredentialsSecretForSwxfll 返回随机生成凭据的 kubernetes.io/basic-auth Secret 对象，
+// 用户名带有随机后缀，轮换后上一个凭据仍然可以保留在密码文件中
+func (r *SwxfllReconciler) generatedCredentialsSecretForSwxfll(swxfll *cachev1beta1.Swxfll) (*corev1.Secret, error) {
+	suffix := make([]byte, 4)
+	password := make([]byte, 24)
+	for _, b := range [][]byte{suffix, password} {
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+	}
+
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      authSecretNameForSwxfll(swxfll),
+			Namespace: swxfll.Namespace,
+			Labels:    selectorLabelsForSwxfll(swxfll.Name),
+		},
+		Type: corev1.SecretTypeBasicAuth,
+		Data: map[string][]byte{
+			corev1.BasicAuthUsernameKey: []byte(swxfll.Name + "-" + hex.EncodeToString(suffix)),
+			corev1.BasicAuthPasswordKey: []byte(hex.EncodeToString(password)),
+		},
+	}
+
+	if err := ctrl.SetControllerReference(swxfll, secret, r.Scheme); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// saslSecretForSwxfll 返回挂载到 memcached 容器中的 SASL 配置和密码文件的 Secret 对象，
+// rotated 记录在注解中，用于滚动更新工作负载。
+func (r *SwxfllReconciler) saslSecretForSwxfll(swxfll *cachev1beta1.Swxfll, pwdb string, rotated time.Time) (*corev1.Secret, error) {
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        saslSecretName(swxfll.Name),
+			Namespace:   swxfll.Namespace,
+			Labels:      selectorLabelsForSwxfll(swxfll.Name),
+			Annotations: map[string]string{credentialsRotatedAnnotation: rotated.UTC().Format(time.RFC3339)},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			saslConfigKey:     []byte("mech_list: plain\n"),
+			saslPasswordDBKey: []byte(pwdb),
+		},
+	}
+
+	if err := ctrl.SetControllerReference(swxfll, secret, r.Scheme); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+	"github.com/swxfll/operator-sdk-demo/internal/memcached"
+)
+
+var _ = Describe("Auth", func() {
+	newSwxfll := func() *cachev1beta1.Swxfll {
+		return &cachev1beta1.Swxfll{
+			ObjectMeta: metav1.ObjectMeta{Name: "sessions", Namespace: "cache"},
+			Spec: cachev1beta1.SwxfllSpec{
+				Size: 1,
+				Auth: &cachev1beta1.AuthSpec{Enabled: true},
+			},
+		}
+	}
+
+	newReconciler := func() *SwxfllReconciler {
+		scheme := runtime.NewScheme()
+		Expect(cachev1beta1.AddToScheme(scheme)).To(Succeed())
+		return &SwxfllReconciler{Scheme: scheme}
+	}
+
+	BeforeEach(func() {
+		Expect(os.Setenv("SWXFLL_IMAGE", "example.com/image:test")).To(Succeed())
+		DeferCleanup(os.Unsetenv, "SWXFLL_IMAGE")
+	})
+
+	It("should start memcached with SASL using the rendered password file", func() {
+		dep, err := newReconciler().deploymentForSwxfll(newSwxfll())
+		Expect(err).NotTo(HaveOccurred())
+		container := dep.Spec.Template.Spec.Containers[0]
+		Expect(container.Args).To(ContainElement("-S"))
+		Expect(container.Env).To(ContainElements(
+			corev1.EnvVar{Name: "SASL_CONF_PATH", Value: saslMountPath},
+			corev1.EnvVar{Name: "MEMCACHED_SASL_PWDB", Value: "/etc/memcached/sasl/pwdb"},
+		))
+		Expect(dep.Spec.Template.Spec.Volumes).To(ContainElement(HaveField("Secret.SecretName", "sessions-sasl")))
+	})
+
+	It("should roll the pods when the credentials or the certificate change", func() {
+		r := newReconciler()
+		swxfll := newSwxfll()
+		swxfll.Spec.TLS = &cachev1beta1.TLSSpec{Enabled: true}
+		swxfll.Status.TLS = &cachev1beta1.TLSStatus{SerialNumber: "1"}
+		rotated := metav1.NewTime(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+		swxfll.Status.Auth = &cachev1beta1.AuthStatus{LastRotationTime: &rotated}
+
+		dep, err := r.deploymentForSwxfll(swxfll)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Spec.Template.Annotations).To(Equal(map[string]string{
+			credentialsRotatedAnnotation: "2024-05-01T00:00:00Z",
+			tlsSerialAnnotation:          "1",
+		}))
+
+		swxfll.Status.TLS.SerialNumber = "2"
+		renewed, err := r.deploymentForSwxfll(swxfll)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(renewed.Spec.Template.Annotations).To(HaveKeyWithValue(tlsSerialAnnotation, "2"))
+	})
+
+	It("should reject a change of the password alone and keep the previous credentials", func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(cachev1beta1.AddToScheme(scheme)).To(Succeed())
+		swxfll := newSwxfll()
+		swxfll.Spec.Auth.SecretName = "sessions-credentials"
+		credentials := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "sessions-credentials", Namespace: "cache"},
+			Data: map[string][]byte{
+				corev1.BasicAuthUsernameKey: []byte("app"),
+				corev1.BasicAuthPasswordKey: []byte("new"),
+			},
+		}
+		sasl := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: saslSecretName("sessions"), Namespace: "cache"},
+			Data:       map[string][]byte{saslPasswordDBKey: []byte("app:old\n")},
+		}
+		recorder := record.NewFakeRecorder(10)
+		r := &SwxfllReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(swxfll, credentials, sasl).Build(),
+			Scheme:   scheme,
+			Recorder: recorder,
+		}
+		ctx := context.Background()
+		passwordDB := func() []string {
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(sasl), sasl)).To(Succeed())
+			return passwordDBEntries(sasl)
+		}
+
+		current, err := r.reconcileAuth(ctx, swxfll, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(current).To(Equal(&memcached.Credentials{Username: "app", Password: "old"}))
+		Expect(passwordDB()).To(Equal([]string{"app:old"}))
+		degraded := meta.FindStatusCondition(swxfll.Status.Conditions, typeDegradedSwxfll)
+		Expect(degraded).NotTo(BeNil())
+		Expect(degraded.Reason).To(Equal(passwordOnlyRotationReason))
+		Expect(recorder.Events).To(Receive(ContainSubstring(passwordOnlyRotationReason)))
+
+		By("changing the username too")
+		credentials.Data[corev1.BasicAuthUsernameKey] = []byte("app-2")
+		Expect(r.Update(ctx, credentials)).To(Succeed())
+		current, err = r.reconcileAuth(ctx, swxfll, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(current).To(Equal(&memcached.Credentials{Username: "app-2", Password: "new"}))
+		Expect(passwordDB()).To(Equal([]string{"app-2:new", "app:old"}))
+		Expect(meta.FindStatusCondition(swxfll.Status.Conditions, typeDegradedSwxfll)).To(BeNil())
+		Expect(swxfll.Status.Auth.PreviousUsername).To(Equal("app"))
+	})
+
+	It("should parse the password file", func() {
+		sasl := &corev1.Secret{Data: map[string][]byte{saslPasswordDBKey: []byte("new:secret\nold:secret:with:colons\n")}}
+		entries := passwordDBEntries(sasl)
+		Expect(entries).To(Equal([]string{"new:secret", "old:secret:with:colons"}))
+		Expect(usernameOf(entries[1])).To(Equal("old"))
+		Expect(passwordDBEntries(nil)).To(BeEmpty())
+		Expect(passwordDBEntries(&corev1.Secret{})).To(BeEmpty())
+	})
+})
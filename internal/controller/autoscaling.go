@@ -0,0 +1,166 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+const (
+	// autoscalingTolerance 是指标与目标值之间可以忽略的相对偏差，避免在目标值附近来回扩缩容
+	autoscalingTolerance = 0.1
+	// maxScalingDecisions 是状态中保留的扩缩容决策数量
+	maxScalingDecisions = 10
+
+	defaultScaleUpCooldown   = 60 * time.Second
+	defaultScaleDownCooldown = 300 * time.Second
+)
+
+// autoscale 根据最新的统计信息评估 Spec.Autoscaling，需要调整大小时返回扩缩容决策。
+// 副本数超出 [minReplicas, maxReplicas] 时不论统计信息和冷却时间都会立即调整到边界；
+// 否则只有当统计信息来自全部 Size 个 Pod 时才会计算，避免在扩缩容或滚动更新的过程中根据不完整的数据做决定。
+func autoscale(swxfll *cachev1beta1.Swxfll, now time.Time) (cachev1beta1.ScalingDecision, bool) {
+	spec := swxfll.Spec.Autoscaling
+	current := swxfll.Spec.Size
+	minReplicas, maxReplicas := spec.MinReplicas, spec.MaxReplicas
+	if minReplicas < 1 {
+		minReplicas = 1
+	}
+	if maxReplicas < minReplicas {
+		maxReplicas = minReplicas
+	}
+
+	decision := cachev1beta1.ScalingDecision{Time: metav1.NewTime(now), FromReplicas: current}
+	switch {
+	case current < minReplicas:
+		decision.ToReplicas = minReplicas
+		decision.Reason = "BelowMinReplicas"
+		decision.Message = fmt.Sprintf("size %d is below minReplicas %d", current, minReplicas)
+		return decision, true
+	case current > maxReplicas:
+		decision.ToReplicas = maxReplicas
+		decision.Reason = "AboveMaxReplicas"
+		decision.Message = fmt.Sprintf("size %d is above maxReplicas %d", current, maxReplicas)
+		return decision, true
+	}
+
+	stats := swxfll.Status.Stats
+	if stats == nil || stats.Pods != current {
+		return decision, false
+	}
+
+	// 每个目标各自给出建议的副本数，取最大值；没有任何建议时保持不变
+	desired := int32(-1)
+	propose := func(ratio float64, reason, message string) {
+		if math.Abs(ratio-1) <= autoscalingTolerance {
+			return
+		}
+		replicas := int32(math.Ceil(float64(current) * ratio))
+		if replicas > desired {
+			desired = replicas
+			decision.Reason = reason
+			decision.Message = message
+		}
+	}
+
+	// 驱逐率低于目标值不代表容量过剩（空闲的缓存同样没有驱逐），因此只用于扩容
+	if target := spec.TargetEvictionsPerSecond; target != nil && stats.EvictionsPerSecond != "" {
+		rate, err := strconv.ParseFloat(stats.EvictionsPerSecond, 64)
+		if want := target.AsApproximateFloat64(); err == nil && want > 0 && rate > want {
+			propose(rate/want, "EvictionRateAboveTarget",
+				fmt.Sprintf("eviction rate %s/s is above the target of %s/s", stats.EvictionsPerSecond, target.String()))
+		}
+	}
+	if target := spec.TargetMemoryUtilizationPercent; target != nil && *target > 0 && stats.LimitBytes > 0 {
+		utilization := float64(stats.BytesUsed) * 100 / float64(stats.LimitBytes)
+		reason := "MemoryUtilizationAboveTarget"
+		if utilization < float64(*target) {
+			reason = "MemoryUtilizationBelowTarget"
+		}
+		propose(utilization/float64(*target), reason,
+			fmt.Sprintf("memory utilization %.1f%% is off the target of %d%%", utilization, *target))
+	}
+
+	if desired < 0 {
+		return decision, false
+	}
+	if desired < minReplicas {
+		desired = minReplicas
+	}
+	if desired > maxReplicas {
+		desired = maxReplicas
+	}
+	if desired == current {
+		return decision, false
+	}
+
+	// 冷却时间从上一次扩缩容决策开始计算
+	cooldown := durationOrDefault(spec.ScaleUpCooldownSeconds, defaultScaleUpCooldown)
+	if desired < current {
+		cooldown = durationOrDefault(spec.ScaleDownCooldownSeconds, defaultScaleDownCooldown)
+	}
+	if status := swxfll.Status.Autoscaling; status != nil && status.LastScaleTime != nil &&
+		now.Before(status.LastScaleTime.Add(cooldown)) {
+		return decision, false
+	}
+
+	decision.ToReplicas = desired
+	return decision, true
+}
+
+// durationOrDefault 将以秒为单位的可选配置转换为 time.Duration
+func durationOrDefault(seconds *int32, def time.Duration) time.Duration {
+	if seconds == nil {
+		return def
+	}
+	return time.Duration(*seconds) * time.Second
+}
+
+// recordScalingDecision 将决策追加到状态中，只保留最近的 maxScalingDecisions 条
+func recordScalingDecision(swxfll *cachev1beta1.Swxfll, decision cachev1beta1.ScalingDecision) {
+	if swxfll.Status.Autoscaling == nil {
+		swxfll.Status.Autoscaling = &cachev1beta1.AutoscalingStatus{}
+	}
+	status := swxfll.Status.Autoscaling
+	status.LastScaleTime = decision.Time.DeepCopy()
+	status.Decisions = append(status.Decisions, decision)
+	if n := len(status.Decisions); n > maxScalingDecisions {
+		status.Decisions = status.Decisions[n-maxScalingDecisions:]
+	}
+}
+
+// scaleSwxfll 修改 Spec.Size，就像 kubectl scale 通过 scale 子资源所做的那样，operator 因此成为 spec.size 的管理者。
+// Deployment 会在 generation 变化触发的下一次调和中更新。
+func (r *SwxfllReconciler) scaleSwxfll(ctx context.Context, swxfll *cachev1beta1.Swxfll, replicas int32) error {
+	scaled := swxfll.DeepCopy()
+	scaled.Spec.Size = replicas
+	if err := r.Patch(ctx, scaled, client.MergeFrom(swxfll)); err != nil {
+		return err
+	}
+	// 只同步 spec，保留本次调和中已经计算好的状态
+	swxfll.Spec.Size = replicas
+	return nil
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+var _ = Describe("autoscale", func() {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// newSwxfll 返回 size 个 Pod 都已上报统计信息的 Swxfll，内存使用率为 usedPercent
+	newSwxfll := func(size int32, usedPercent int64, evictionsPerSecond string) *cachev1beta1.Swxfll {
+		return &cachev1beta1.Swxfll{
+			Spec: cachev1beta1.SwxfllSpec{
+				Size: size,
+				Autoscaling: &cachev1beta1.AutoscalingSpec{
+					MinReplicas:                    1,
+					MaxReplicas:                    5,
+					TargetEvictionsPerSecond:       ptrTo(resource.MustParse("10")),
+					TargetMemoryUtilizationPercent: ptrTo(int32(80)),
+				},
+			},
+			Status: cachev1beta1.SwxfllStatus{Stats: &cachev1beta1.CacheStats{
+				Pods:               size,
+				EvictionsPerSecond: evictionsPerSecond,
+				BytesUsed:          usedPercent << 20,
+				LimitBytes:         100 << 20,
+			}},
+		}
+	}
+
+	It("should scale up proportionally to the eviction rate", func() {
+		decision, ok := autoscale(newSwxfll(2, 80, "25"), now)
+		Expect(ok).To(BeTrue())
+		Expect(decision.FromReplicas).To(Equal(int32(2)))
+		Expect(decision.ToReplicas).To(Equal(int32(5)))
+		Expect(decision.Reason).To(Equal("EvictionRateAboveTarget"))
+		Expect(decision.Time.Time).To(Equal(now))
+	})
+
+	It("should not exceed maxReplicas", func() {
+		decision, ok := autoscale(newSwxfll(4, 100, "1000"), now)
+		Expect(ok).To(BeTrue())
+		Expect(decision.ToReplicas).To(Equal(int32(5)))
+	})
+
+	It("should scale down on low memory utilization only when evictions are within target", func() {
+		decision, ok := autoscale(newSwxfll(4, 20, "0"), now)
+		Expect(ok).To(BeTrue())
+		Expect(decision.ToReplicas).To(Equal(int32(1)))
+		Expect(decision.Reason).To(Equal("MemoryUtilizationBelowTarget"))
+
+		decision, ok = autoscale(newSwxfll(4, 20, "30"), now)
+		Expect(ok).To(BeTrue())
+		Expect(decision.ToReplicas).To(Equal(int32(5)))
+	})
+
+	It("should ignore deviations within the tolerance", func() {
+		_, ok := autoscale(newSwxfll(3, 84, "10.5"), now)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should wait for the stats of every pod", func() {
+		swxfll := newSwxfll(3, 100, "100")
+		swxfll.Status.Stats.Pods = 2
+		_, ok := autoscale(swxfll, now)
+		Expect(ok).To(BeFalse())
+
+		swxfll.Status.Stats = nil
+		_, ok = autoscale(swxfll, now)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should respect the cooldowns since the last decision", func() {
+		swxfll := newSwxfll(2, 20, "0")
+		swxfll.Status.Autoscaling = &cachev1beta1.AutoscalingStatus{
+			LastScaleTime: &metav1.Time{Time: now.Add(-2 * time.Minute)},
+		}
+		_, ok := autoscale(swxfll, now)
+		Expect(ok).To(BeFalse(), "scale down cooldown defaults to 5 minutes")
+
+		swxfll = newSwxfll(2, 100, "50")
+		swxfll.Status.Autoscaling = &cachev1beta1.AutoscalingStatus{
+			LastScaleTime: &metav1.Time{Time: now.Add(-2 * time.Minute)},
+		}
+		_, ok = autoscale(swxfll, now)
+		Expect(ok).To(BeTrue(), "scale up cooldown defaults to 1 minute")
+	})
+
+	It("should move the size into the bounds without stats", func() {
+		swxfll := newSwxfll(1, 0, "")
+		swxfll.Spec.Autoscaling.MinReplicas = 3
+		swxfll.Status.Stats = nil
+		decision, ok := autoscale(swxfll, now)
+		Expect(ok).To(BeTrue())
+		Expect(decision.ToReplicas).To(Equal(int32(3)))
+		Expect(decision.Reason).To(Equal("BelowMinReplicas"))
+	})
+
+	It("should keep only the latest decisions in the status", func() {
+		swxfll := &cachev1beta1.Swxfll{}
+		for i := int32(0); i < maxScalingDecisions+2; i++ {
+			recordScalingDecision(swxfll, cachev1beta1.ScalingDecision{
+				Time: metav1.NewTime(now.Add(time.Duration(i) * time.Minute)), FromReplicas: i, ToReplicas: i + 1,
+			})
+		}
+		Expect(swxfll.Status.Autoscaling.Decisions).To(HaveLen(maxScalingDecisions))
+		Expect(swxfll.Status.Autoscaling.Decisions[0].FromReplicas).To(Equal(int32(2)))
+		Expect(swxfll.Status.Autoscaling.LastScaleTime.Time).To(Equal(now.Add(11 * time.Minute)))
+	})
+})
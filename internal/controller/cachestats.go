@@ -0,0 +1,117 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/tls"
+	"strconv"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+	"github.com/swxfll/operator-sdk-demo/internal/memcached"
+)
+
+// statsTimeout 是从单个 Pod 获取统计信息的超时时间，超时的 Pod 不计入汇总
+const statsTimeout = 2 * time.Second
+
+// collectStats 并发地通过 memcached 文本协议从所有就绪的 Pod 获取统计信息并汇总，
+// 开启 TLS 时 tlsConfig 不为空，开启认证时 credentials 不为空。没有任何 Pod 返回统计信息时返回 nil
+func (r *SwxfllReconciler) collectStats(ctx context.Context, endpoints []string, tlsConfig *tls.Config,
+	credentials *memcached.Credentials) *cachev1beta1.CacheStats {
+	log := log.FromContext(ctx)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	scraped := make([]*memcached.Stats, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		wg.Add(1)
+		go func(endpoint string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, statsTimeout)
+			defer cancel()
+
+			stats, err := memcached.FetchStats(ctx, endpoint, tlsConfig, credentials)
+			if err != nil {
+				log.V(1).Info("无法获取 memcached 统计信息", "endpoint", endpoint, "error", err.Error())
+				return
+			}
+			mu.Lock()
+			scraped = append(scraped, stats)
+			mu.Unlock()
+		}(endpoint)
+	}
+	wg.Wait()
+
+	return aggregateStats(scraped, metav1.Now())
+}
+
+// statsScrapeWait 返回距离下一次采集统计信息还需要等待的时间，返回 0 时应当立即采集。
+// Pod 的变化同样会触发调和，只按 interval 采集可以保证两次采集的间隔足够计算每秒淘汰数。
+func statsScrapeWait(previous *cachev1beta1.CacheStats, interval time.Duration, now time.Time) time.Duration {
+	if previous == nil {
+		return 0
+	}
+	if wait := interval - now.Sub(previous.LastScrapeTime.Time); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// evictionsPerSecond 根据上一次和本次采集的淘汰总数计算每秒淘汰数。
+// 两次采集的间隔不足一秒，或者 Pod 重启导致计数器变小时返回空字符串。
+func evictionsPerSecond(previous, current *cachev1beta1.CacheStats) string {
+	if previous == nil || current == nil {
+		return ""
+	}
+	elapsed := current.LastScrapeTime.Sub(previous.LastScrapeTime.Time).Seconds()
+	evicted := current.Evictions - previous.Evictions
+	if elapsed < 1 || evicted < 0 {
+		return ""
+	}
+	return strconv.FormatFloat(float64(evicted)/elapsed, 'f', 2, 64)
+}
+
+// aggregateStats 将各个 Pod 的统计信息相加，并计算整体的命中率
+func aggregateStats(scraped []*memcached.Stats, now metav1.Time) *cachev1beta1.CacheStats {
+	if len(scraped) == 0 {
+		return nil
+	}
+
+	total := &cachev1beta1.CacheStats{
+		Pods:           int32(len(scraped)),
+		LastScrapeTime: now,
+	}
+	for _, stats := range scraped {
+		total.GetHits += stats.GetHits
+		total.GetMisses += stats.GetMisses
+		total.Evictions += stats.Evictions
+		total.CurrentItems += stats.CurrItems
+		total.BytesUsed += stats.Bytes
+		total.LimitBytes += stats.LimitMaxBytes
+		total.CurrentConnections += stats.CurrConnections
+		total.MaxConnections += stats.MaxConnections
+	}
+	if gets := total.GetHits + total.GetMisses; gets > 0 {
+		total.HitRatio = strconv.FormatFloat(float64(total.GetHits)/float64(gets), 'f', 4, 64)
+	}
+	return total
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/swxfll/operator-sdk-demo/internal/memcached"
+	"github.com/swxfll/operator-sdk-demo/internal/memcached/memcachedtest"
+)
+
+var _ = Describe("aggregateStats", func() {
+	It("should sum the stats of every pod and compute the hit ratio", func() {
+		now := metav1.Now()
+		stats := aggregateStats([]*memcached.Stats{
+			{GetHits: 70, GetMisses: 10, Evictions: 1, CurrItems: 5, Bytes: 100, LimitMaxBytes: 1000, CurrConnections: 3, MaxConnections: 10},
+			{GetHits: 20, GetMisses: 0, Evictions: 2, CurrItems: 7, Bytes: 300, LimitMaxBytes: 1000, CurrConnections: 4, MaxConnections: 10},
+		}, now)
+		Expect(stats.Pods).To(Equal(int32(2)))
+		Expect(stats.HitRatio).To(Equal("0.9000"))
+		Expect(stats.Evictions).To(Equal(int64(3)))
+		Expect(stats.CurrentItems).To(Equal(int64(12)))
+		Expect(stats.BytesUsed).To(Equal(int64(400)))
+		Expect(stats.LimitBytes).To(Equal(int64(2000)))
+		Expect(stats.CurrentConnections).To(Equal(int64(7)))
+		Expect(stats.MaxConnections).To(Equal(int64(20)))
+		Expect(stats.LastScrapeTime).To(Equal(now))
+	})
+
+	It("should leave the hit ratio empty before the first get", func() {
+		stats := aggregateStats([]*memcached.Stats{{}}, metav1.Now())
+		Expect(stats.HitRatio).To(BeEmpty())
+	})
+
+	It("should return nil without any scraped pod", func() {
+		Expect(aggregateStats(nil, metav1.Now())).To(BeNil())
+	})
+})
+
+var _ = Describe("collectStats", func() {
+	It("should skip the pods that cannot be scraped", func() {
+		server, err := memcachedtest.NewServer(map[string]string{
+			"get_hits":   "3",
+			"get_misses": "1",
+		}, map[string]string{"maxconns": "1024"})
+		Expect(err).NotTo(HaveOccurred())
+		defer server.Close()
+
+		stats := (&SwxfllReconciler{}).collectStats(context.Background(), []string{server.Addr, "127.0.0.1:1"}, nil, nil)
+		Expect(stats).NotTo(BeNil())
+		Expect(stats.Pods).To(Equal(int32(1)))
+		Expect(stats.HitRatio).To(Equal("0.7500"))
+		Expect(stats.MaxConnections).To(Equal(int64(1024)))
+	})
+})
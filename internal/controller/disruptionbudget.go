@@ -0,0 +1,109 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+// disruptionBudgetEnabled 返回是否需要为 swxfll 创建 PodDisruptionBudget。
+// 只有一个实例时 PodDisruptionBudget 只会让节点无法排空，因此不创建。
+func disruptionBudgetEnabled(swxfll *cachev1beta1.Swxfll) bool {
+	return swxfll.Spec.Size > 1
+}
+
+// defaultMaxUnavailable 返回未设置 Spec.DisruptionBudget 时允许同时驱逐的实例数：Size 的四分之一，至少为 1。
+// 每驱逐一个实例就有 1/Size 的 key 失效，限制同时驱逐的实例数可以避免所有请求同时落到后端数据库。
+func defaultMaxUnavailable(size int32) int32 {
+	if size/4 > 1 {
+		return size / 4
+	}
+	return 1
+}
+
+// reconcileDisruptionBudget 在 Size 大于 1 时创建或更新 swxfll 拥有的 PodDisruptionBudget，否则删除它
+func (r *SwxfllReconciler) reconcileDisruptionBudget(ctx context.Context, swxfll *cachev1beta1.Swxfll) error {
+	if !disruptionBudgetEnabled(swxfll) {
+		pdb := &policyv1.PodDisruptionBudget{}
+		err := r.Get(ctx, types.NamespacedName{Name: swxfll.Name, Namespace: swxfll.Namespace}, pdb)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		if !metav1.IsControlledBy(pdb, swxfll) {
+			return nil
+		}
+		if err := r.Delete(ctx, pdb); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	pdb, err := r.disruptionBudgetForSwxfll(swxfll)
+	if err != nil {
+		return err
+	}
+	return r.apply(ctx, pdb)
+}
+
+// disruptionBudgetForSwxfll 返回限制同时驱逐的实例数的 PodDisruptionBudget 对象，mcrouter 的 Pod 不在其中
+func (r *SwxfllReconciler) disruptionBudgetForSwxfll(swxfll *cachev1beta1.Swxfll) (*policyv1.PodDisruptionBudget, error) {
+	spec := policyv1.PodDisruptionBudgetSpec{
+		Selector: &metav1.LabelSelector{MatchLabels: selectorLabelsForSwxfll(swxfll.Name)},
+	}
+	db := swxfll.Spec.DisruptionBudget
+	switch {
+	case db != nil && db.MinAvailable != nil:
+		minAvailable := *db.MinAvailable
+		spec.MinAvailable = &minAvailable
+	case db != nil && db.MaxUnavailable != nil:
+		maxUnavailable := *db.MaxUnavailable
+		spec.MaxUnavailable = &maxUnavailable
+	default:
+		maxUnavailable := intstr.FromInt(int(defaultMaxUnavailable(swxfll.Spec.Size)))
+		spec.MaxUnavailable = &maxUnavailable
+	}
+
+	pdb := &policyv1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: policyv1.SchemeGroupVersion.String(),
+			Kind:       "PodDisruptionBudget",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      swxfll.Name,
+			Namespace: swxfll.Namespace,
+			Labels:    selectorLabelsForSwxfll(swxfll.Name),
+		},
+		Spec: spec,
+	}
+
+	if err := ctrl.SetControllerReference(swxfll, pdb, r.Scheme); err != nil {
+		return nil, err
+	}
+	return pdb, nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+var _ = Describe("PodDisruptionBudget", func() {
+	var r *SwxfllReconciler
+	var swxfll *cachev1beta1.Swxfll
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(cachev1beta1.AddToScheme(scheme)).To(Succeed())
+		r = &SwxfllReconciler{Scheme: scheme}
+		swxfll = &cachev1beta1.Swxfll{
+			ObjectMeta: metav1.ObjectMeta{Name: "sessions", Namespace: "cache"},
+			Spec:       cachev1beta1.SwxfllSpec{Size: 3},
+		}
+	})
+
+	It("should not be created for a single instance", func() {
+		swxfll.Spec.Size = 1
+		Expect(disruptionBudgetEnabled(swxfll)).To(BeFalse())
+	})
+
+	It("should evict a quarter of the instances at once by default", func() {
+		for size, want := range map[int32]int32{2: 1, 7: 1, 8: 2, 20: 5} {
+			swxfll.Spec.Size = size
+			pdb, err := r.disruptionBudgetForSwxfll(swxfll)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pdb.Spec.Selector.MatchLabels).To(Equal(selectorLabelsForSwxfll("sessions")))
+			Expect(pdb.Spec.MinAvailable).To(BeNil())
+			Expect(*pdb.Spec.MaxUnavailable).To(Equal(intstr.FromInt(int(want))), "size %d", size)
+		}
+	})
+
+	It("should use the configured budget", func() {
+		swxfll.Spec.DisruptionBudget = &cachev1beta1.DisruptionBudgetSpec{MinAvailable: ptrTo(intstr.FromString("50%"))}
+		pdb, err := r.disruptionBudgetForSwxfll(swxfll)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pdb.Spec.MaxUnavailable).To(BeNil())
+		Expect(*pdb.Spec.MinAvailable).To(Equal(intstr.FromString("50%")))
+
+		swxfll.Spec.DisruptionBudget = &cachev1beta1.DisruptionBudgetSpec{MaxUnavailable: ptrTo(intstr.FromInt(2))}
+		pdb, err = r.disruptionBudgetForSwxfll(swxfll)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pdb.Spec.MinAvailable).To(BeNil())
+		Expect(*pdb.Spec.MaxUnavailable).To(Equal(intstr.FromInt(2)))
+	})
+})
@@ -0,0 +1,120 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// workloadDrift 比较期望的 Deployment 或 StatefulSet 与集群中实际的对象，
+// 返回所有与期望状态不一致的 operator 管理字段的路径。
+// 只比较由 deploymentForSwxfll 渲染的字段，其他控制器或 API Server 默认填充的字段不参与比较。
+func workloadDrift(desired, found client.Object) []string {
+	var drifted []string
+
+	// 副本数只由 operator 根据 Spec.Size 写入，手工 scale 工作负载或以工作负载为目标的 HPA 都会被视为漂移
+	if !equality.Semantic.DeepEqual(replicasOf(found), replicasOf(desired)) {
+		drifted = append(drifted, "spec.replicas")
+	}
+	if !containsLabels(found.GetLabels(), desired.GetLabels()) {
+		drifted = append(drifted, "metadata.labels")
+	}
+
+	want, got := podTemplateOf(desired), podTemplateOf(found)
+	if !containsLabels(got.Labels, want.Labels) {
+		drifted = append(drifted, "spec.template.metadata.labels")
+	}
+	if !equality.Semantic.DeepEqual(got.Spec.SecurityContext, want.Spec.SecurityContext) {
+		drifted = append(drifted, "spec.template.spec.securityContext")
+	}
+	if !equality.Semantic.DeepEqual(got.Spec.NodeSelector, want.Spec.NodeSelector) {
+		drifted = append(drifted, "spec.template.spec.nodeSelector")
+	}
+	if !equality.Semantic.DeepEqual(got.Spec.Tolerations, want.Spec.Tolerations) {
+		drifted = append(drifted, "spec.template.spec.tolerations")
+	}
+	if !equality.Semantic.DeepEqual(got.Spec.Affinity, want.Spec.Affinity) {
+		drifted = append(drifted, "spec.template.spec.affinity")
+	}
+	if got.Spec.PriorityClassName != want.Spec.PriorityClassName {
+		drifted = append(drifted, "spec.template.spec.priorityClassName")
+	}
+	if !equality.Semantic.DeepEqual(got.Spec.TopologySpreadConstraints, want.Spec.TopologySpreadConstraints) {
+		drifted = append(drifted, "spec.template.spec.topologySpreadConstraints")
+	}
+	return append(drifted, containersDrift(want.Spec.Containers, got.Spec.Containers)...)
+}
+
+// containersDrift 比较期望的容器与实际的容器，返回不一致字段的路径
+func containersDrift(desired, found []corev1.Container) []string {
+	var drifted []string
+	for i := range desired {
+		want := &desired[i]
+		got := findContainer(found, want.Name)
+		prefix := "spec.template.spec.containers[" + want.Name + "]"
+		if got == nil {
+			drifted = append(drifted, prefix)
+			continue
+		}
+		if got.Image != want.Image {
+			drifted = append(drifted, prefix+".image")
+		}
+		if got.ImagePullPolicy != want.ImagePullPolicy {
+			drifted = append(drifted, prefix+".imagePullPolicy")
+		}
+		if !equality.Semantic.DeepEqual(got.Command, want.Command) {
+			drifted = append(drifted, prefix+".command")
+		}
+		if !equality.Semantic.DeepEqual(got.Args, want.Args) {
+			drifted = append(drifted, prefix+".args")
+		}
+		if !equality.Semantic.DeepEqual(got.Ports, want.Ports) {
+			drifted = append(drifted, prefix+".ports")
+		}
+		if !equality.Semantic.DeepEqual(got.SecurityContext, want.SecurityContext) {
+			drifted = append(drifted, prefix+".securityContext")
+		}
+		if !equality.Semantic.DeepEqual(got.Resources, want.Resources) {
+			drifted = append(drifted, prefix+".resources")
+		}
+	}
+
+	return drifted
+}
+
+// findContainer 按名称查找容器，找不到时返回 nil
+func findContainer(containers []corev1.Container, name string) *corev1.Container {
+	for i := range containers {
+		if containers[i].Name == name {
+			return &containers[i]
+		}
+	}
+	return nil
+}
+
+// containsLabels 判断 got 是否包含 want 中的全部标签。
+// 其他工具额外添加的标签不视为漂移。
+func containsLabels(got, want map[string]string) bool {
+	for k, v := range want {
+		if got[k] != v {
+			return false
+		}
+	}
+	return true
+}
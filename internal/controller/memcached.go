@@ -0,0 +1,90 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+const (
+	// memcachedCommand 是 operand 容器运行的命令，默认镜像（上游 memcached 镜像）中的可执行文件
+	memcachedCommand = "memcached"
+	// defaultMemoryMB 是未设置 Spec.Memcached.MemoryMB 时 memcached 使用的内存大小
+	defaultMemoryMB = 64
+	// defaultVerbosity 是未设置 Spec.Memcached.Verbosity 时的日志级别，对应 -v
+	defaultVerbosity = 1
+	// minItemSize 和 maxItemSize 是 memcached 允许的 -I 取值范围
+	minItemSize = 1 << 10
+	maxItemSize = 1 << 30
+)
+
+// memoryMBForSwxfll 返回 memcached 用于存储数据的内存大小（MB）
+func memoryMBForSwxfll(swxfll *cachev1beta1.Swxfll) int32 {
+	if spec := swxfll.Spec.Memcached; spec != nil && spec.MemoryMB > 0 {
+		return spec.MemoryMB
+	}
+	return defaultMemoryMB
+}
+
+// memcachedArgs 根据 Spec.Memcached 渲染 memcached 的命令行参数。
+// 参数变化会改变 Pod 模板，从而触发 Deployment 的滚动更新。
+func memcachedArgs(swxfll *cachev1beta1.Swxfll) ([]string, error) {
+	spec := swxfll.Spec.Memcached
+	if spec == nil {
+		spec = &cachev1beta1.MemcachedSpec{}
+	}
+	memoryMB := memoryMBForSwxfll(swxfll)
+
+	args := []string{fmt.Sprintf("-m=%d", memoryMB), "-o", "modern"}
+	if spec.MaxConnections > 0 {
+		args = append(args, fmt.Sprintf("-c=%d", spec.MaxConnections))
+	}
+	if spec.MaxItemSize != nil {
+		size := spec.MaxItemSize.Value()
+		if size < minItemSize || size > maxItemSize {
+			return nil, fmt.Errorf("memcached.maxItemSize %s must be between 1Ki and 1Gi", spec.MaxItemSize.String())
+		}
+		// memcached 拒绝大于 -m 一半的 -I
+		if size > int64(memoryMB)<<20/2 {
+			return nil, fmt.Errorf("memcached.maxItemSize %s must not exceed half of memcached.memoryMB (%dMB)",
+				spec.MaxItemSize.String(), memoryMB)
+		}
+		args = append(args, fmt.Sprintf("-I=%d", size))
+	}
+	if spec.Threads > 0 {
+		args = append(args, fmt.Sprintf("-t=%d", spec.Threads))
+	}
+	verbosity := int32(defaultVerbosity)
+	if spec.Verbosity != nil {
+		verbosity = *spec.Verbosity
+	}
+	if verbosity > 0 {
+		args = append(args, "-"+strings.Repeat("v", int(verbosity)))
+	}
+
+	if tlsEnabled(swxfll) {
+		args = append(args, tlsArgs()...)
+	}
+	if authEnabled(swxfll) {
+		args = append(args, authArgs()...)
+	}
+
+	return append(args, spec.ExtraArgs...), nil
+}
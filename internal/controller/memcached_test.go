@@ -0,0 +1,64 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+var _ = Describe("memcachedArgs", func() {
+	It("should keep the historical command line when nothing is configured", func() {
+		args, err := memcachedArgs(&cachev1beta1.Swxfll{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args).To(Equal([]string{"-m=64", "-o", "modern", "-v"}))
+	})
+
+	It("should render every tuning parameter", func() {
+		args, err := memcachedArgs(&cachev1beta1.Swxfll{Spec: cachev1beta1.SwxfllSpec{
+			Memcached: &cachev1beta1.MemcachedSpec{
+				MemoryMB:       256,
+				MaxConnections: 4096,
+				MaxItemSize:    ptrTo(resource.MustParse("2Mi")),
+				Threads:        8,
+				Verbosity:      ptrTo(int32(0)),
+				ExtraArgs:      []string{"--disable-flush-all"},
+			},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args).To(Equal([]string{
+			"-m=256", "-o", "modern", "-c=4096", "-I=2097152", "-t=8", "--disable-flush-all",
+		}))
+	})
+
+	It("should reject an item size larger than half of the memory", func() {
+		_, err := memcachedArgs(&cachev1beta1.Swxfll{Spec: cachev1beta1.SwxfllSpec{
+			Memcached: &cachev1beta1.MemcachedSpec{MemoryMB: 8, MaxItemSize: ptrTo(resource.MustParse("8Mi"))},
+		}})
+		Expect(err).To(MatchError(ContainSubstring("half of memcached.memoryMB")))
+	})
+
+	It("should reject an item size outside of the memcached limits", func() {
+		_, err := memcachedArgs(&cachev1beta1.Swxfll{Spec: cachev1beta1.SwxfllSpec{
+			Memcached: &cachev1beta1.MemcachedSpec{MaxItemSize: ptrTo(resource.MustParse("512"))},
+		}})
+		Expect(err).To(MatchError(ContainSubstring("between 1Ki and 1Gi")))
+	})
+})
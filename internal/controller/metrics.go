@@ -0,0 +1,104 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+// 每个 Swxfll 的 operand 健康指标，通过 manager 的 metrics 端点暴露，不需要为每个实例运行 exporter sidecar
+var (
+	hitRatioGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "swxfll_hit_ratio",
+		Help: "Ratio of get requests that found an item, across the ready pods of the Swxfll",
+	}, []string{"namespace", "name"})
+	evictionsPerSecondGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "swxfll_evictions_per_second",
+		Help: "Valid items evicted per second between the last two stats scrapes of the Swxfll",
+	}, []string{"namespace", "name"})
+	memoryUtilizationGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "swxfll_memory_utilization_ratio",
+		Help: "Ratio of the item storage memory in use, across the ready pods of the Swxfll",
+	}, []string{"namespace", "name"})
+	connectionSaturationGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "swxfll_connection_saturation_ratio",
+		Help: "Ratio of open client connections to the connection limit, across the ready pods of the Swxfll",
+	}, []string{"namespace", "name"})
+	readyPodsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "swxfll_ready_pods",
+		Help: "Number of ready pods of the Swxfll",
+	}, []string{"namespace", "name"})
+
+	statsGauges = []*prometheus.GaugeVec{
+		hitRatioGauge, evictionsPerSecondGauge, memoryUtilizationGauge, connectionSaturationGauge,
+	}
+)
+
+func init() {
+	// 注册到 controller-runtime 的全局 Registry，与控制器自身的指标一起暴露
+	metrics.Registry.MustRegister(hitRatioGauge, evictionsPerSecondGauge, memoryUtilizationGauge,
+		connectionSaturationGauge, readyPodsGauge)
+}
+
+// recordMetrics 根据 swxfll 的状态更新它的指标。
+// 没有统计信息时删除由统计信息计算的指标，避免一直暴露过期的值。
+func recordMetrics(swxfll *cachev1beta1.Swxfll) {
+	namespace, name := swxfll.Namespace, swxfll.Name
+	readyPodsGauge.WithLabelValues(namespace, name).Set(float64(swxfll.Status.ReadyReplicas))
+
+	stats := swxfll.Status.Stats
+	if stats == nil {
+		for _, gauge := range statsGauges {
+			gauge.DeleteLabelValues(namespace, name)
+		}
+		return
+	}
+
+	if ratio, err := strconv.ParseFloat(stats.HitRatio, 64); err == nil {
+		hitRatioGauge.WithLabelValues(namespace, name).Set(ratio)
+	} else {
+		hitRatioGauge.DeleteLabelValues(namespace, name)
+	}
+	if rate, err := strconv.ParseFloat(stats.EvictionsPerSecond, 64); err == nil {
+		evictionsPerSecondGauge.WithLabelValues(namespace, name).Set(rate)
+	} else {
+		evictionsPerSecondGauge.DeleteLabelValues(namespace, name)
+	}
+	if stats.LimitBytes > 0 {
+		memoryUtilizationGauge.WithLabelValues(namespace, name).Set(float64(stats.BytesUsed) / float64(stats.LimitBytes))
+	} else {
+		memoryUtilizationGauge.DeleteLabelValues(namespace, name)
+	}
+	if stats.MaxConnections > 0 {
+		connectionSaturationGauge.WithLabelValues(namespace, name).
+			Set(float64(stats.CurrentConnections) / float64(stats.MaxConnections))
+	} else {
+		connectionSaturationGauge.DeleteLabelValues(namespace, name)
+	}
+}
+
+// deleteMetrics 删除 swxfll 的全部指标，在自定义资源被删除时调用
+func deleteMetrics(swxfll *cachev1beta1.Swxfll) {
+	for _, gauge := range append(statsGauges, readyPodsGauge) {
+		gauge.DeleteLabelValues(swxfll.Namespace, swxfll.Name)
+	}
+}
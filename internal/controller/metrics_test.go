@@ -0,0 +1,112 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+var _ = Describe("recordMetrics", func() {
+	newSwxfll := func() *cachev1beta1.Swxfll {
+		return &cachev1beta1.Swxfll{
+			ObjectMeta: metav1.ObjectMeta{Name: "sessions", Namespace: "cache"},
+			Status: cachev1beta1.SwxfllStatus{
+				ReadyReplicas: 3,
+				Stats: &cachev1beta1.CacheStats{
+					HitRatio:           "0.9500",
+					EvictionsPerSecond: "2.50",
+					BytesUsed:          256,
+					LimitBytes:         1024,
+					CurrentConnections: 10,
+					MaxConnections:     40,
+				},
+			},
+		}
+	}
+
+	AfterEach(func() {
+		deleteMetrics(newSwxfll())
+	})
+
+	It("should export the cache health of the Swxfll labelled by namespace and name", func() {
+		recordMetrics(newSwxfll())
+
+		Expect(testutil.ToFloat64(readyPodsGauge.WithLabelValues("cache", "sessions"))).To(Equal(3.0))
+		Expect(testutil.ToFloat64(hitRatioGauge.WithLabelValues("cache", "sessions"))).To(Equal(0.95))
+		Expect(testutil.ToFloat64(evictionsPerSecondGauge.WithLabelValues("cache", "sessions"))).To(Equal(2.5))
+		Expect(testutil.ToFloat64(memoryUtilizationGauge.WithLabelValues("cache", "sessions"))).To(Equal(0.25))
+		Expect(testutil.ToFloat64(connectionSaturationGauge.WithLabelValues("cache", "sessions"))).To(Equal(0.25))
+	})
+
+	It("should drop the stats gauges once stats are unavailable", func() {
+		swxfll := newSwxfll()
+		recordMetrics(swxfll)
+		swxfll.Status.Stats = nil
+		recordMetrics(swxfll)
+
+		Expect(testutil.CollectAndCount(hitRatioGauge)).To(BeZero())
+		Expect(testutil.CollectAndCount(readyPodsGauge)).To(Equal(1))
+
+		deleteMetrics(swxfll)
+		Expect(testutil.CollectAndCount(readyPodsGauge)).To(BeZero())
+	})
+
+	It("should drop the saturation gauges once their limits are unknown", func() {
+		swxfll := newSwxfll()
+		recordMetrics(swxfll)
+		swxfll.Status.Stats.LimitBytes = 0
+		swxfll.Status.Stats.MaxConnections = 0
+		recordMetrics(swxfll)
+
+		Expect(testutil.CollectAndCount(memoryUtilizationGauge)).To(BeZero())
+		Expect(testutil.CollectAndCount(connectionSaturationGauge)).To(BeZero())
+		Expect(testutil.CollectAndCount(hitRatioGauge)).To(Equal(1))
+	})
+})
+
+var _ = Describe("evictionsPerSecond", func() {
+	It("should compute the rate between two scrapes", func() {
+		start := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		previous := &cachev1beta1.CacheStats{Evictions: 100, LastScrapeTime: start}
+		current := &cachev1beta1.CacheStats{Evictions: 400, LastScrapeTime: metav1.NewTime(start.Add(time.Minute))}
+		Expect(evictionsPerSecond(previous, current)).To(Equal("5.00"))
+	})
+
+	It("should only scrape again once the interval has passed", func() {
+		start := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		previous := &cachev1beta1.CacheStats{LastScrapeTime: start}
+		Expect(statsScrapeWait(nil, 30*time.Second, start.Time)).To(BeZero())
+		Expect(statsScrapeWait(previous, 30*time.Second, start.Add(400*time.Millisecond))).
+			To(Equal(29600 * time.Millisecond))
+		Expect(statsScrapeWait(previous, 30*time.Second, start.Add(30*time.Second))).To(BeZero())
+		Expect(statsScrapeWait(previous, 30*time.Second, start.Add(time.Hour))).To(BeZero())
+	})
+
+	It("should not report a rate on the first scrape or after a counter reset", func() {
+		start := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		current := &cachev1beta1.CacheStats{Evictions: 10, LastScrapeTime: metav1.NewTime(start.Add(time.Minute))}
+		Expect(evictionsPerSecond(nil, current)).To(BeEmpty())
+		Expect(evictionsPerSecond(&cachev1beta1.CacheStats{Evictions: 50, LastScrapeTime: start}, current)).To(BeEmpty())
+	})
+})
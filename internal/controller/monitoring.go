@@ -0,0 +1,224 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+const (
+	// exporterContainerName 是 exporter sidecar 的容器名称
+	exporterContainerName = "exporter"
+	// metricsPortName 是 exporter 容器端口和 headless Service 端口的名称，ServiceMonitor 按名称选择端口
+	metricsPortName = "metrics"
+	// exporterUserID 是 exporter 镜像中 nobody 用户的 UID。
+	// 镜像以用户名声明 USER，runAsNonRoot 无法校验非数字的用户，因此需要显式指定 UID
+	exporterUserID int64 = 65534
+)
+
+// serviceMonitorGVK 是 Prometheus Operator 的 ServiceMonitor 类型。
+// 集群中不一定安装了该 CRD，因此不引入它的 Go 类型，而是使用 unstructured 对象
+var serviceMonitorGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}
+
+// monitoringEnabled 返回是否为 swxfll 开启了监控
+func monitoringEnabled(swxfll *cachev1beta1.Swxfll) bool {
+	return swxfll.Spec.Monitoring != nil && swxfll.Spec.Monitoring.Enabled
+}
+
+// exporterImageForSwxfll 返回 exporter sidecar 的镜像，
+// 未设置 Spec.Monitoring.Image 时使用 config/manager/manager.yaml 中定义的 SWXFLL_EXPORTER_IMAGE 环境变量
+func exporterImageForSwxfll(swxfll *cachev1beta1.Swxfll) (string, error) {
+	if image := swxfll.Spec.Monitoring.Image; image != "" {
+		return image, nil
+	}
+	var imageEnvVar = "SWXFLL_EXPORTER_IMAGE"
+	image, found := os.LookupEnv(imageEnvVar)
+	if !found {
+		return "", fmt.Errorf("无法找到 %s 环境变量与镜像", imageEnvVar)
+	}
+	return image, nil
+}
+
+// exporterResourcesForSwxfll 返回 exporter 容器的资源配置，未设置时使用较小的固定值，避免 Pod 降级为 BestEffort
+func exporterResourcesForSwxfll(swxfll *cachev1beta1.Swxfll) corev1.ResourceRequirements {
+	if res := swxfll.Spec.Monitoring.Resources; res != nil {
+		return *res.DeepCopy()
+	}
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("10m"),
+			corev1.ResourceMemory: resource.MustParse("32Mi"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("64Mi"),
+		},
+	}
+}
+
+// exporterContainerForSwxfll 返回 memcached exporter sidecar 容器。
+// exporter 通过 localhost 连接同一 Pod 中的 memcached，并在 MetricsPort 上暴露 Prometheus 指标
+func exporterContainerForSwxfll(swxfll *cachev1beta1.Swxfll) (corev1.Container, error) {
+	image, err := exporterImageForSwxfll(swxfll)
+	if err != nil {
+		return corev1.Container{}, err
+	}
+
+	container := corev1.Container{
+		Image:           image,
+		Name:            exporterContainerName,
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		SecurityContext: &corev1.SecurityContext{
+			RunAsUser:                &[]int64{exporterUserID}[0],
+			AllowPrivilegeEscalation: &[]bool{false}[0],
+			Capabilities: &corev1.Capabilities{
+				Drop: []corev1.Capability{
+					"ALL",
+				},
+			},
+		},
+		Ports: []corev1.ContainerPort{{
+			ContainerPort: cachev1beta1.MetricsPort,
+			Name:          metricsPortName,
+			Protocol:      corev1.ProtocolTCP,
+		}},
+		Args: []string{
+			"--memcached.address=localhost:" + strconv.Itoa(int(portForSwxfll(swxfll))),
+			"--web.listen-address=:" + strconv.Itoa(int(cachev1beta1.MetricsPort)),
+		},
+		Resources: exporterResourcesForSwxfll(swxfll),
+	}
+
+	// 开启 TLS 后 memcached 只接受 TLS 连接，exporter 使用挂载的 ca.crt 校验证书
+	if tlsEnabled(swxfll) {
+		container.Args = append(container.Args,
+			"--memcached.tls.enable",
+			"--memcached.tls.server-name="+tlsVerifyName(swxfll),
+			"--memcached.tls.ca-file="+tlsMountPath+"/"+tlsCAKey,
+		)
+		container.VolumeMounts = []corev1.VolumeMount{{Name: tlsVolumeName, MountPath: tlsMountPath, ReadOnly: true}}
+	}
+	return container, nil
+}
+
+// metricsServicePort 返回 headless Service 上指向 exporter 的端口。
+// 指标只通过 headless Service 暴露，不会随 NodePort 或 LoadBalancer 类型的客户端 Service 暴露到集群外
+func metricsServicePort() corev1.ServicePort {
+	return corev1.ServicePort{
+		Name:       metricsPortName,
+		Port:       cachev1beta1.MetricsPort,
+		TargetPort: intstr.FromString(metricsPortName),
+		Protocol:   corev1.ProtocolTCP,
+	}
+}
+
+// reconcileServiceMonitor 在开启监控时创建或更新 swxfll 拥有的 ServiceMonitor，并记录在状态中，关闭监控时删除它。
+// 集群中没有安装 ServiceMonitor CRD 时跳过，CRD 之后被安装时会在下一次调和中创建。
+// ServiceMonitor 不在缓存中，只有状态中记录了 ServiceMonitor 时才会读取并删除它，关闭监控时不会产生 API 请求。
+func (r *SwxfllReconciler) reconcileServiceMonitor(ctx context.Context, swxfll *cachev1beta1.Swxfll) error {
+	log := log.FromContext(ctx)
+
+	if !monitoringEnabled(swxfll) {
+		if swxfll.Status.ServiceMonitor == "" {
+			return nil
+		}
+		sm := &unstructured.Unstructured{}
+		sm.SetGroupVersionKind(serviceMonitorGVK)
+		err := r.Get(ctx, types.NamespacedName{Name: swxfll.Status.ServiceMonitor, Namespace: swxfll.Namespace}, sm)
+		if err != nil && !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return err
+		}
+		// 只删除 swxfll 拥有的 ServiceMonitor，不删除用户创建的同名对象
+		if err == nil && metav1.IsControlledBy(sm, swxfll) {
+			if err := r.Delete(ctx, sm); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+		swxfll.Status.ServiceMonitor = ""
+		return nil
+	}
+
+	sm, err := r.serviceMonitorForSwxfll(swxfll)
+	if err != nil {
+		return err
+	}
+	if err := r.apply(ctx, sm); err != nil {
+		if meta.IsNoMatchError(err) {
+			log.V(1).Info("ServiceMonitor CRD 未安装，跳过创建 ServiceMonitor",
+				"ServiceMonitor.Namespace", swxfll.Namespace, "ServiceMonitor.Name", swxfll.Name)
+			return nil
+		}
+		return err
+	}
+	swxfll.Status.ServiceMonitor = sm.GetName()
+	return nil
+}
+
+// serviceMonitorForSwxfll 返回选择 headless Service metrics 端口的 ServiceMonitor 对象。
+// 两个 Service 的标签相同，但只有 headless Service 有 metrics 端口，因此每个 Pod 只会被抓取一次
+func (r *SwxfllReconciler) serviceMonitorForSwxfll(swxfll *cachev1beta1.Swxfll) (*unstructured.Unstructured, error) {
+	ls := map[string]string{}
+	endpoint := map[string]interface{}{
+		"port": metricsPortName,
+	}
+	if spec := swxfll.Spec.Monitoring.ServiceMonitor; spec != nil {
+		for k, v := range spec.Labels {
+			ls[k] = v
+		}
+		if spec.Interval != "" {
+			endpoint["interval"] = spec.Interval
+		}
+	}
+	matchLabels := map[string]interface{}{}
+	for k, v := range selectorLabelsForSwxfll(swxfll.Name) {
+		ls[k] = v
+		matchLabels[k] = v
+	}
+
+	sm := &unstructured.Unstructured{}
+	sm.SetGroupVersionKind(serviceMonitorGVK)
+	sm.SetName(swxfll.Name)
+	sm.SetNamespace(swxfll.Namespace)
+	sm.SetLabels(ls)
+	sm.Object["spec"] = map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": matchLabels,
+		},
+		"endpoints": []interface{}{endpoint},
+	}
+
+	if err := ctrl.SetControllerReference(swxfll, sm, r.Scheme); err != nil {
+		return nil, err
+	}
+	return sm, nil
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+var _ = Describe("Monitoring", func() {
+	ctx := context.Background()
+
+	newSwxfll := func(monitoring *cachev1beta1.MonitoringSpec) *cachev1beta1.Swxfll {
+		swxfll := &cachev1beta1.Swxfll{Spec: cachev1beta1.SwxfllSpec{
+			Networking: &cachev1beta1.NetworkingSpec{Port: 11311},
+			Monitoring: monitoring,
+		}}
+		swxfll.Name = "cache"
+		swxfll.Namespace = "default"
+		return swxfll
+	}
+
+	newReconciler := func() *SwxfllReconciler {
+		scheme := runtime.NewScheme()
+		Expect(cachev1beta1.AddToScheme(scheme)).To(Succeed())
+		return &SwxfllReconciler{Scheme: scheme}
+	}
+
+	AfterEach(func() {
+		Expect(os.Unsetenv("SWXFLL_EXPORTER_IMAGE")).To(Succeed())
+	})
+
+	It("should point the exporter at the memcached port of the same pod", func() {
+		Expect(os.Setenv("SWXFLL_EXPORTER_IMAGE", "example.com/exporter:default")).To(Succeed())
+
+		container, err := exporterContainerForSwxfll(newSwxfll(&cachev1beta1.MonitoringSpec{Enabled: true}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(container.Image).To(Equal("example.com/exporter:default"))
+		Expect(container.Args).To(ConsistOf("--memcached.address=localhost:11311", "--web.listen-address=:9150"))
+		Expect(container.Ports).To(ConsistOf(HaveField("ContainerPort", cachev1beta1.MetricsPort)))
+		Expect(container.Resources.Limits.Memory().String()).To(Equal("64Mi"))
+	})
+
+	It("should prefer the image from the spec and fail without any image", func() {
+		container, err := exporterContainerForSwxfll(newSwxfll(&cachev1beta1.MonitoringSpec{
+			Enabled: true,
+			Image:   "example.com/exporter:custom",
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(container.Image).To(Equal("example.com/exporter:custom"))
+
+		_, err = exporterContainerForSwxfll(newSwxfll(&cachev1beta1.MonitoringSpec{Enabled: true}))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should render a ServiceMonitor selecting the metrics port", func() {
+		r := newReconciler()
+		sm, err := r.serviceMonitorForSwxfll(newSwxfll(&cachev1beta1.MonitoringSpec{
+			Enabled: true,
+			ServiceMonitor: &cachev1beta1.ServiceMonitorSpec{
+				Interval: "15s",
+				Labels:   map[string]string{"release": "prometheus"},
+			},
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sm.GroupVersionKind()).To(Equal(serviceMonitorGVK))
+		Expect(sm.GetLabels()).To(HaveKeyWithValue("release", "prometheus"))
+		Expect(sm.GetOwnerReferences()).To(HaveLen(1))
+
+		endpoints, _, _ := unstructured.NestedSlice(sm.Object, "spec", "endpoints")
+		Expect(endpoints).To(ConsistOf(map[string]interface{}{"port": metricsPortName, "interval": "15s"}))
+		matchLabels, _, _ := unstructured.NestedStringMap(sm.Object, "spec", "selector", "matchLabels")
+		Expect(matchLabels).To(Equal(selectorLabelsForSwxfll("cache")))
+	})
+
+	It("should only delete the ServiceMonitor it owns when monitoring is disabled", func() {
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(serviceMonitorGVK, meta.RESTScopeNamespace)
+		swxfll := newSwxfll(nil)
+		swxfll.UID = "uid"
+
+		userSM := &unstructured.Unstructured{}
+		userSM.SetGroupVersionKind(serviceMonitorGVK)
+		userSM.SetName(swxfll.Name)
+		userSM.SetNamespace(swxfll.Namespace)
+
+		r := newReconciler()
+		r.Client = fake.NewClientBuilder().WithScheme(r.Scheme).WithRESTMapper(mapper).WithObjects(userSM).Build()
+		swxfll.Status.ServiceMonitor = swxfll.Name
+		Expect(r.reconcileServiceMonitor(ctx, swxfll)).To(Succeed())
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(userSM), userSM.DeepCopy())).To(Succeed())
+		Expect(swxfll.Status.ServiceMonitor).To(BeEmpty())
+
+		ownedSM := userSM.DeepCopy()
+		Expect(ctrl.SetControllerReference(swxfll, ownedSM, r.Scheme)).To(Succeed())
+		r.Client = fake.NewClientBuilder().WithScheme(r.Scheme).WithRESTMapper(mapper).WithObjects(ownedSM).Build()
+		swxfll.Status.ServiceMonitor = swxfll.Name
+		Expect(r.reconcileServiceMonitor(ctx, swxfll)).To(Succeed())
+		err := r.Get(ctx, client.ObjectKeyFromObject(ownedSM), ownedSM.DeepCopy())
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(swxfll.Status.ServiceMonitor).To(BeEmpty())
+	})
+
+	It("should not call the API server when monitoring stays disabled", func() {
+		r := newReconciler()
+		r.Client = fake.NewClientBuilder().WithScheme(r.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(context.Context, client.WithWatch, client.ObjectKey, client.Object, ...client.GetOption) error {
+				Fail("unexpected GET")
+				return nil
+			},
+		}).Build()
+		Expect(r.reconcileServiceMonitor(ctx, newSwxfll(nil))).To(Succeed())
+	})
+
+	It("should only expose the metrics port on the headless Service", func() {
+		r := newReconciler()
+		swxfll := newSwxfll(&cachev1beta1.MonitoringSpec{Enabled: true})
+
+		svc, err := r.serviceForSwxfll(swxfll)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(svc.Spec.Ports).To(HaveLen(1))
+
+		headless, err := r.headlessServiceForSwxfll(swxfll)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(headless.Spec.Ports).To(ContainElement(corev1.ServicePort{
+			Name: metricsPortName, Port: cachev1beta1.MetricsPort,
+			TargetPort: metricsServicePort().TargetPort, Protocol: corev1.ProtocolTCP,
+		}))
+	})
+})
@@ -0,0 +1,141 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+// operatorPodLabels 是 operator Pod 的标签，与 config/manager/manager.yaml 中的 Pod 模板一致
+var operatorPodLabels = map[string]string{"control-plane": "controller-manager"}
+
+// networkPolicyEnabled 返回是否需要为 swxfll 创建 NetworkPolicy
+func networkPolicyEnabled(swxfll *cachev1beta1.Swxfll) bool {
+	return len(swxfll.Spec.AllowedClients) > 0
+}
+
+// networkPolicyPeers 将 NetworkPeer 转换为 NetworkPolicyPeer。
+// 两个 selector 都为空的 NetworkPeer 表示 Swxfll 所在命名空间的所有 Pod。
+func networkPolicyPeers(peers []cachev1beta1.NetworkPeer) []networkingv1.NetworkPolicyPeer {
+	var result []networkingv1.NetworkPolicyPeer
+	for _, peer := range peers {
+		p := networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: peer.NamespaceSelector.DeepCopy(),
+			PodSelector:       peer.PodSelector.DeepCopy(),
+		}
+		if p.NamespaceSelector == nil && p.PodSelector == nil {
+			p.PodSelector = &metav1.LabelSelector{}
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// reconcileNetworkPolicy 在设置了 Spec.AllowedClients 时创建或更新 swxfll 拥有的 NetworkPolicy，未设置时删除它
+func (r *SwxfllReconciler) reconcileNetworkPolicy(ctx context.Context, swxfll *cachev1beta1.Swxfll) error {
+	if !networkPolicyEnabled(swxfll) {
+		np := &networkingv1.NetworkPolicy{}
+		err := r.Get(ctx, types.NamespacedName{Name: swxfll.Name, Namespace: swxfll.Namespace}, np)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		if !metav1.IsControlledBy(np, swxfll) {
+			return nil
+		}
+		if err := r.Delete(ctx, np); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	np, err := r.networkPolicyForSwxfll(swxfll)
+	if err != nil {
+		return err
+	}
+	return r.apply(ctx, np)
+}
+
+// networkPolicyForSwxfll 返回只允许 Spec.AllowedClients、mcrouter 和 operator 连接 memcached 端口，
+// 只允许 Spec.Monitoring.AllowedScrapers 连接指标端口的 NetworkPolicy 对象。
+// operator 需要连接 memcached 端口采集统计信息和重新加载证书。
+func (r *SwxfllReconciler) networkPolicyForSwxfll(swxfll *cachev1beta1.Swxfll) (*networkingv1.NetworkPolicy, error) {
+	tcp := corev1.ProtocolTCP
+	memcachedPort := intstr.FromInt(int(portForSwxfll(swxfll)))
+
+	clients := networkPolicyPeers(swxfll.Spec.AllowedClients)
+	if proxyEnabled(swxfll) {
+		clients = append(clients, networkingv1.NetworkPolicyPeer{
+			PodSelector: &metav1.LabelSelector{MatchLabels: proxySelectorLabelsForSwxfll(swxfll.Name)},
+		})
+	}
+	if r.OperatorNamespace != "" {
+		clients = append(clients, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{
+				corev1.LabelMetadataName: r.OperatorNamespace,
+			}},
+			PodSelector: &metav1.LabelSelector{MatchLabels: operatorPodLabels},
+		})
+	}
+
+	ingress := []networkingv1.NetworkPolicyIngressRule{{
+		From:  clients,
+		Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &memcachedPort}},
+	}}
+	if monitoringEnabled(swxfll) {
+		metricsPort := intstr.FromInt(int(cachev1beta1.MetricsPort))
+		ingress = append(ingress, networkingv1.NetworkPolicyIngressRule{
+			// From 为空时允许所有来源
+			From:  networkPolicyPeers(swxfll.Spec.Monitoring.AllowedScrapers),
+			Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &metricsPort}},
+		})
+	}
+
+	np := &networkingv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: networkingv1.SchemeGroupVersion.String(),
+			Kind:       "NetworkPolicy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      swxfll.Name,
+			Namespace: swxfll.Namespace,
+			Labels:    selectorLabelsForSwxfll(swxfll.Name),
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: selectorLabelsForSwxfll(swxfll.Name)},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     ingress,
+		},
+	}
+
+	if err := ctrl.SetControllerReference(swxfll, np, r.Scheme); err != nil {
+		return nil, err
+	}
+	return np, nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+var _ = Describe("NetworkPolicy", func() {
+	web := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	frontend := &metav1.LabelSelector{MatchLabels: map[string]string{"team": "frontend"}}
+
+	var r *SwxfllReconciler
+	var swxfll *cachev1beta1.Swxfll
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(cachev1beta1.AddToScheme(scheme)).To(Succeed())
+		r = &SwxfllReconciler{Scheme: scheme}
+		swxfll = &cachev1beta1.Swxfll{
+			ObjectMeta: metav1.ObjectMeta{Name: "sessions", Namespace: "cache"},
+			Spec: cachev1beta1.SwxfllSpec{
+				AllowedClients: []cachev1beta1.NetworkPeer{
+					{PodSelector: web},
+					{NamespaceSelector: frontend},
+					{},
+				},
+			},
+		}
+	})
+
+	port := func(p int32) []networkingv1.NetworkPolicyPort {
+		return []networkingv1.NetworkPolicyPort{{Protocol: ptrTo(corev1.ProtocolTCP), Port: ptrTo(intstr.FromInt(int(p)))}}
+	}
+
+	It("should only admit the allowed clients on the memcached port", func() {
+		np, err := r.networkPolicyForSwxfll(swxfll)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(np.Spec.PodSelector.MatchLabels).To(Equal(selectorLabelsForSwxfll("sessions")))
+		Expect(np.Spec.PolicyTypes).To(ConsistOf(networkingv1.PolicyTypeIngress))
+		Expect(np.Spec.Ingress).To(HaveLen(1))
+		Expect(np.Spec.Ingress[0].Ports).To(Equal(port(cachev1beta1.DefaultContainerPort)))
+		// 两个 selector 都为空的 peer 表示 Swxfll 所在命名空间的所有 Pod
+		Expect(np.Spec.Ingress[0].From).To(Equal([]networkingv1.NetworkPolicyPeer{
+			{PodSelector: web},
+			{NamespaceSelector: frontend},
+			{PodSelector: &metav1.LabelSelector{}},
+		}))
+	})
+
+	It("should admit the proxy, the operator and the scrapers", func() {
+		r.OperatorNamespace = "swxfll-system"
+		swxfll.Spec.AllowedClients = swxfll.Spec.AllowedClients[:1]
+		swxfll.Spec.Proxy = &cachev1beta1.ProxySpec{Enabled: true}
+		swxfll.Spec.Monitoring = &cachev1beta1.MonitoringSpec{
+			Enabled:         true,
+			AllowedScrapers: []cachev1beta1.NetworkPeer{{NamespaceSelector: frontend, PodSelector: web}},
+		}
+
+		np, err := r.networkPolicyForSwxfll(swxfll)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(np.Spec.Ingress).To(HaveLen(2))
+		Expect(np.Spec.Ingress[0].From).To(Equal([]networkingv1.NetworkPolicyPeer{
+			{PodSelector: web},
+			{PodSelector: &metav1.LabelSelector{MatchLabels: proxySelectorLabelsForSwxfll("sessions")}},
+			{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{
+					corev1.LabelMetadataName: "swxfll-system",
+				}},
+				PodSelector: &metav1.LabelSelector{MatchLabels: operatorPodLabels},
+			},
+		}))
+		Expect(np.Spec.Ingress[1].Ports).To(Equal(port(cachev1beta1.MetricsPort)))
+		Expect(np.Spec.Ingress[1].From).To(Equal([]networkingv1.NetworkPolicyPeer{
+			{NamespaceSelector: frontend, PodSelector: web},
+		}))
+	})
+
+	It("should admit every scraper when none is listed", func() {
+		swxfll.Spec.Monitoring = &cachev1beta1.MonitoringSpec{Enabled: true}
+
+		np, err := r.networkPolicyForSwxfll(swxfll)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(np.Spec.Ingress).To(HaveLen(2))
+		Expect(np.Spec.Ingress[1].From).To(BeEmpty())
+	})
+})
@@ -0,0 +1,100 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+// versionLabel 是记录 operand 版本的标签，Pod 模板上的值用于判断滚动更新的目标版本
+const versionLabel = "app.kubernetes.io/version"
+
+// operandForSwxfll 返回 swxfll 实例应运行的 operand 镜像和版本。
+// Spec.Image 优先于默认镜像；Spec.Version 会替换默认镜像的标签，并且必须在 operator 支持的版本列表中。
+func operandForSwxfll(swxfll *cachev1beta1.Swxfll) (image, version string, err error) {
+	if v := swxfll.Spec.Version; v != "" {
+		if supported := supportedVersionsForSwxfll(); len(supported) > 0 && !containsString(supported, v) {
+			return "", "", fmt.Errorf("version %s is not supported by the operator, supported versions: %s",
+				v, strings.Join(supported, ", "))
+		}
+	}
+
+	if swxfll.Spec.Image != "" {
+		image = swxfll.Spec.Image
+		version = swxfll.Spec.Version
+		if version == "" {
+			_, version = splitImage(image)
+		}
+		return image, version, nil
+	}
+
+	defaultImage, err := imageForSwxfll()
+	if err != nil {
+		return "", "", err
+	}
+	repo, tag := splitImage(defaultImage)
+	if v := swxfll.Spec.Version; v != "" {
+		return repo + ":" + v, v, nil
+	}
+	return defaultImage, tag, nil
+}
+
+// DefaultVersion 返回 operator 默认镜像（SWXFLL_IMAGE）的版本，未配置或镜像没有标签时返回空字符串
+func DefaultVersion() string {
+	image, err := imageForSwxfll()
+	if err != nil {
+		return ""
+	}
+	_, tag := splitImage(image)
+	return tag
+}
+
+// supportedVersionsForSwxfll 从 config/manager/manager.yaml 中定义的 SWXFLL_SUPPORTED_VERSIONS
+// 环境变量（逗号分隔）中获取 operator 支持的 operand 版本列表。未设置时不限制版本。
+func supportedVersionsForSwxfll() []string {
+	var versions []string
+	for _, v := range strings.Split(os.Getenv("SWXFLL_SUPPORTED_VERSIONS"), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// splitImage 将镜像引用拆分为仓库和标签，能正确处理带端口的 registry，使用 digest 的引用没有标签
+func splitImage(image string) (repo, tag string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		return image[:i], ""
+	}
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		return image[:colon], image[colon+1:]
+	}
+	return image, ""
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+var _ = Describe("operandForSwxfll", func() {
+	BeforeEach(func() {
+		Expect(os.Setenv("SWXFLL_IMAGE", "registry.example.com:5000/memcached:1.6.22")).To(Succeed())
+		Expect(os.Setenv("SWXFLL_SUPPORTED_VERSIONS", "1.6.22, 1.6.23")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.Unsetenv("SWXFLL_IMAGE")).To(Succeed())
+		Expect(os.Unsetenv("SWXFLL_SUPPORTED_VERSIONS")).To(Succeed())
+	})
+
+	It("should use the operator default when nothing is set", func() {
+		image, version, err := operandForSwxfll(&cachev1beta1.Swxfll{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(image).To(Equal("registry.example.com:5000/memcached:1.6.22"))
+		Expect(version).To(Equal("1.6.22"))
+	})
+
+	It("should replace the tag of the default image with a supported version", func() {
+		image, version, err := operandForSwxfll(&cachev1beta1.Swxfll{
+			Spec: cachev1beta1.SwxfllSpec{Version: "1.6.23"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(image).To(Equal("registry.example.com:5000/memcached:1.6.23"))
+		Expect(version).To(Equal("1.6.23"))
+	})
+
+	It("should reject versions outside of the allowlist", func() {
+		_, _, err := operandForSwxfll(&cachev1beta1.Swxfll{
+			Spec: cachev1beta1.SwxfllSpec{Version: "1.5.0"},
+		})
+		Expect(err).To(MatchError(ContainSubstring("not supported")))
+	})
+
+	It("should prefer the image set on the custom resource", func() {
+		image, version, err := operandForSwxfll(&cachev1beta1.Swxfll{
+			Spec: cachev1beta1.SwxfllSpec{Image: "mirror.example.com/memcached:1.6.23"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(image).To(Equal("mirror.example.com/memcached:1.6.23"))
+		Expect(version).To(Equal("1.6.23"))
+	})
+
+	It("should run a command that exists in the default image of the manager", func() {
+		manifest, err := os.ReadFile(filepath.Join("..", "..", "config", "manager", "manager.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+
+		var manager appsv1.Deployment
+		for _, doc := range bytes.Split(manifest, []byte("\n---\n")) {
+			if bytes.Contains(doc, []byte("kind: Deployment")) {
+				Expect(yaml.Unmarshal(doc, &manager)).To(Succeed())
+			}
+		}
+		Expect(manager.Spec.Template.Spec.Containers).NotTo(BeEmpty())
+
+		var defaultImage string
+		for _, env := range manager.Spec.Template.Spec.Containers[0].Env {
+			if env.Name == "SWXFLL_IMAGE" {
+				defaultImage = env.Value
+			}
+		}
+		Expect(os.Setenv("SWXFLL_IMAGE", defaultImage)).To(Succeed())
+
+		image, _, err := operandForSwxfll(&cachev1beta1.Swxfll{})
+		Expect(err).NotTo(HaveOccurred())
+		// 上游 memcached 镜像只包含 memcached 可执行文件，修改默认镜像时需要同时检查 Command
+		repo, _ := splitImage(image)
+		Expect(repo).To(Equal(memcachedCommand))
+
+		scheme := runtime.NewScheme()
+		Expect(cachev1beta1.AddToScheme(scheme)).To(Succeed())
+		r := &SwxfllReconciler{Scheme: scheme}
+		dep, err := r.deploymentForSwxfll(&cachev1beta1.Swxfll{ObjectMeta: metav1.ObjectMeta{Name: "sessions", Namespace: "cache"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Spec.Template.Spec.Containers[0].Image).To(Equal(image))
+		Expect(dep.Spec.Template.Spec.Containers[0].Command).To(Equal([]string{memcachedCommand}))
+	})
+})
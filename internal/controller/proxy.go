@@ -0,0 +1,389 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+const (
+	// proxyConfigHashAnnotation 记录 Pod 模板使用的 mcrouter 配置的哈希，配置变化时 Deployment 会滚动更新
+	proxyConfigHashAnnotation = "cache.swxfll.com/config-hash"
+	// proxyConfigKey 是 ConfigMap 中 mcrouter 配置的键
+	proxyConfigKey = "config.json"
+	// proxyConfigDir 是 mcrouter 容器中挂载配置的目录
+	proxyConfigDir = "/etc/mcrouter"
+	// proxyTLSDir 是 mcrouter 容器中挂载证书 Secret 的目录
+	proxyTLSDir = "/etc/mcrouter-tls"
+	// proxyUserID 是运行 mcrouter 的非 root 用户
+	proxyUserID int64 = 65534
+	// defaultProxyReplicas 是未设置 Spec.Proxy.Replicas 时 mcrouter 的副本数
+	defaultProxyReplicas int32 = 2
+	// proxyFailoverSalt 是 failover 时重新哈希 key 使用的盐，使重试落在另一个实例上
+	proxyFailoverSalt = "failover"
+)
+
+// proxyName 返回 mcrouter Deployment、Service 和 ConfigMap 的名称
+func proxyName(name string) string {
+	return name + "-mcrouter"
+}
+
+// proxyEnabled 返回是否为 swxfll 开启了 mcrouter 代理
+func proxyEnabled(swxfll *cachev1beta1.Swxfll) bool {
+	return swxfll.Spec.Proxy != nil && swxfll.Spec.Proxy.Enabled
+}
+
+// proxySelectorLabelsForSwxfll 返回 mcrouter Pod 的标签。
+// 它们与缓存 Pod 的 selectorLabelsForSwxfll 不同，因此 mcrouter Pod 不会被缓存的 Service 选中，也不会出现在服务器列表中。
+func proxySelectorLabelsForSwxfll(name string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       "mcrouter",
+		"app.kubernetes.io/instance":   name,
+		"app.kubernetes.io/component":  "proxy",
+		"app.kubernetes.io/part-of":    "swxfll-operator",
+		"app.kubernetes.io/created-by": "controller-manager",
+	}
+}
+
+// proxyPortForSwxfll 返回 mcrouter 监听的端口
+func proxyPortForSwxfll(swxfll *cachev1beta1.Swxfll) int32 {
+	if port := swxfll.Spec.Proxy.Port; port != 0 {
+		return port
+	}
+	return cachev1beta1.DefaultProxyPort
+}
+
+// proxyImageForSwxfll 返回 mcrouter 的镜像，
+// 未设置 Spec.Proxy.Image 时使用 config/manager/manager.yaml 中定义的 SWXFLL_MCROUTER_IMAGE 环境变量
+func proxyImageForSwxfll(swxfll *cachev1beta1.Swxfll) (string, error) {
+	if image := swxfll.Spec.Proxy.Image; image != "" {
+		return image, nil
+	}
+	var imageEnvVar = "SWXFLL_MCROUTER_IMAGE"
+	image, found := os.LookupEnv(imageEnvVar)
+	if !found {
+		return "", fmt.Errorf("无法找到 %s 环境变量与镜像", imageEnvVar)
+	}
+	return image, nil
+}
+
+// mcrouterConfig 返回包含一个名为 pool 的服务器池的 mcrouter 配置。
+// Sharded 使用 PoolRoute 按一致性哈希分片，failover 时用加盐的哈希在同一个池中选择另一个实例重试；
+// Replicated 将写操作同步发送到所有实例，读操作由 LatestRoute 发送到其中一个实例，出错时自动换到其他实例。
+func mcrouterConfig(pool string, servers []string, route cachev1beta1.ProxyRoute, failover bool) (string, error) {
+	if servers == nil {
+		servers = []string{}
+	}
+
+	var routeConfig interface{}
+	switch {
+	case route == cachev1beta1.ProxyRouteReplicated:
+		routeConfig = map[string]interface{}{
+			"type":           "OperationSelectorRoute",
+			"default_policy": "AllSyncRoute|Pool|" + pool,
+			"operation_policies": map[string]interface{}{
+				"get":  "LatestRoute|Pool|" + pool,
+				"gets": "LatestRoute|Pool|" + pool,
+			},
+		}
+	case failover:
+		routeConfig = map[string]interface{}{
+			"type": "FailoverRoute",
+			"children": []interface{}{
+				"PoolRoute|" + pool,
+				map[string]interface{}{
+					"type": "PoolRoute",
+					"pool": pool,
+					"hash": map[string]interface{}{"hash_func": "Ch3", "salt": proxyFailoverSalt},
+				},
+			},
+		}
+	default:
+		routeConfig = "PoolRoute|" + pool
+	}
+
+	config := map[string]interface{}{
+		"pools": map[string]interface{}{
+			pool: map[string]interface{}{"servers": servers},
+		},
+		"route": routeConfig,
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// reconcileProxy 在开启代理时创建或更新 mcrouter 的 ConfigMap、Deployment 和 Service，关闭时删除它们。
+// 配置中的服务器使用就绪 Pod 的 IP，成员变化时配置的哈希随之变化，mcrouter Pod 会滚动更新以加载新的配置。
+func (r *SwxfllReconciler) reconcileProxy(ctx context.Context, swxfll *cachev1beta1.Swxfll, endpoints []string) error {
+	if !proxyEnabled(swxfll) {
+		swxfll.Status.Proxy = nil
+		return r.deleteProxy(ctx, swxfll)
+	}
+
+	servers := endpoints
+	if tlsEnabled(swxfll) {
+		// host:port:protocol:security 格式的服务器让 mcrouter 通过 TLS 连接 memcached
+		servers = make([]string, 0, len(endpoints))
+		for _, endpoint := range endpoints {
+			servers = append(servers, endpoint+":ascii:ssl")
+		}
+	}
+	config, err := mcrouterConfig(swxfll.Name, servers, swxfll.Spec.Proxy.Route, swxfll.Spec.Proxy.Failover)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256([]byte(config))
+	configHash := hex.EncodeToString(sum[:])
+
+	cm, err := r.proxyConfigMapForSwxfll(swxfll, config)
+	if err != nil {
+		return err
+	}
+	if err := r.apply(ctx, cm); err != nil {
+		return err
+	}
+
+	dep, err := r.proxyDeploymentForSwxfll(swxfll, configHash)
+	if err != nil {
+		return err
+	}
+	if err := r.apply(ctx, dep); err != nil {
+		return err
+	}
+
+	svc, err := r.proxyServiceForSwxfll(swxfll)
+	if err != nil {
+		return err
+	}
+	if err := r.apply(ctx, svc); err != nil {
+		return err
+	}
+
+	swxfll.Status.Proxy = &cachev1beta1.ProxyStatus{
+		Endpoint:      fmt.Sprintf("%s.%s.svc:%d", svc.Name, svc.Namespace, proxyPortForSwxfll(swxfll)),
+		ReadyReplicas: dep.Status.ReadyReplicas,
+		ConfigHash:    configHash,
+	}
+	return nil
+}
+
+// deleteProxy 删除关闭代理前创建的 mcrouter 对象，只删除由该 swxfll 拥有的对象
+func (r *SwxfllReconciler) deleteProxy(ctx context.Context, swxfll *cachev1beta1.Swxfll) error {
+	key := types.NamespacedName{Name: proxyName(swxfll.Name), Namespace: swxfll.Namespace}
+	for _, obj := range []client.Object{&appsv1.Deployment{}, &corev1.Service{}, &corev1.ConfigMap{}} {
+		if err := r.Get(ctx, key, obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if !metav1.IsControlledBy(obj, swxfll) {
+			continue
+		}
+		if err := r.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// proxyConfigMapForSwxfll 返回保存 mcrouter 配置的 ConfigMap 对象
+func (r *SwxfllReconciler) proxyConfigMapForSwxfll(swxfll *cachev1beta1.Swxfll, config string) (*corev1.ConfigMap, error) {
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      proxyName(swxfll.Name),
+			Namespace: swxfll.Namespace,
+			Labels:    proxySelectorLabelsForSwxfll(swxfll.Name),
+		},
+		Data: map[string]string{proxyConfigKey: config},
+	}
+
+	if err := ctrl.SetControllerReference(swxfll, cm, r.Scheme); err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
+
+// proxyDeploymentForSwxfll 返回 mcrouter Deployment 对象。
+// Pod 模板上的配置哈希注解使每次配置变化都会触发滚动更新。
+func (r *SwxfllReconciler) proxyDeploymentForSwxfll(swxfll *cachev1beta1.Swxfll, configHash string) (*appsv1.Deployment, error) {
+	image, err := proxyImageForSwxfll(swxfll)
+	if err != nil {
+		return nil, err
+	}
+	replicas := defaultProxyReplicas
+	if swxfll.Spec.Proxy.Replicas != nil {
+		replicas = *swxfll.Spec.Proxy.Replicas
+	}
+	var resources corev1.ResourceRequirements
+	if swxfll.Spec.Proxy.Resources != nil {
+		resources = *swxfll.Spec.Proxy.Resources.DeepCopy()
+	}
+	port := proxyPortForSwxfll(swxfll)
+	ls := proxySelectorLabelsForSwxfll(swxfll.Name)
+
+	dep := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "Deployment",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      proxyName(swxfll.Name),
+			Namespace: swxfll.Namespace,
+			Labels:    ls,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: ls,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      ls,
+					Annotations: map[string]string{proxyConfigHashAnnotation: configHash},
+				},
+				Spec: corev1.PodSpec{
+					SecurityContext: &corev1.PodSecurityContext{
+						RunAsNonRoot: &[]bool{true}[0],
+						SeccompProfile: &corev1.SeccompProfile{
+							Type: corev1.SeccompProfileTypeRuntimeDefault,
+						},
+					},
+					Containers: []corev1.Container{{
+						Image:           image,
+						Name:            "mcrouter",
+						ImagePullPolicy: corev1.PullIfNotPresent,
+						SecurityContext: &corev1.SecurityContext{
+							RunAsUser:                &[]int64{proxyUserID}[0],
+							AllowPrivilegeEscalation: &[]bool{false}[0],
+							Capabilities: &corev1.Capabilities{
+								Drop: []corev1.Capability{
+									"ALL",
+								},
+							},
+						},
+						Ports: []corev1.ContainerPort{{
+							ContainerPort: port,
+							Name:          "mcrouter",
+							Protocol:      corev1.ProtocolTCP,
+						}},
+						Command: []string{"mcrouter"},
+						Args: []string{
+							"--port=" + strconv.Itoa(int(port)),
+							"--config-file=" + proxyConfigDir + "/" + proxyConfigKey,
+						},
+						ReadinessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{
+								TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("mcrouter")},
+							},
+						},
+						Resources: resources,
+						VolumeMounts: []corev1.VolumeMount{
+							{Name: "config", MountPath: proxyConfigDir, ReadOnly: true},
+							// mcrouter 默认将统计信息和异步日志写入 /var/mcrouter 和 /var/spool/mcrouter
+							{Name: "var", MountPath: "/var/mcrouter"},
+							{Name: "spool", MountPath: "/var/spool/mcrouter"},
+						},
+					}},
+					Volumes: []corev1.Volume{
+						{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: proxyName(swxfll.Name)},
+						}}},
+						{Name: "var", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+						{Name: "spool", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+					},
+				},
+			},
+		},
+	}
+
+	// 开启 TLS 时 mcrouter 使用与 memcached 相同的证书连接它，并用 ca.crt 校验 memcached 的证书，
+	// 证书续期后 mcrouter 会滚动更新以加载新的证书
+	if tlsEnabled(swxfll) {
+		spec := &dep.Spec.Template.Spec
+		spec.Volumes = append(spec.Volumes, tlsVolumeForSwxfll(swxfll))
+		spec.Containers[0].VolumeMounts = append(spec.Containers[0].VolumeMounts,
+			corev1.VolumeMount{Name: tlsVolumeName, MountPath: proxyTLSDir, ReadOnly: true})
+		spec.Containers[0].Args = append(spec.Containers[0].Args,
+			"--pem-cert-path="+proxyTLSDir+"/"+corev1.TLSCertKey,
+			"--pem-key-path="+proxyTLSDir+"/"+corev1.TLSPrivateKeyKey,
+			"--pem-ca-path="+proxyTLSDir+"/"+tlsCAKey,
+		)
+		if swxfll.Status.TLS != nil {
+			dep.Spec.Template.Annotations[tlsSerialAnnotation] = swxfll.Status.TLS.SerialNumber
+		}
+	}
+
+	if err := ctrl.SetControllerReference(swxfll, dep, r.Scheme); err != nil {
+		return nil, err
+	}
+	return dep, nil
+}
+
+// proxyServiceForSwxfll 返回客户端连接 mcrouter 使用的 ClusterIP Service 对象
+func (r *SwxfllReconciler) proxyServiceForSwxfll(swxfll *cachev1beta1.Swxfll) (*corev1.Service, error) {
+	svc := &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      proxyName(swxfll.Name),
+			Namespace: swxfll.Namespace,
+			Labels:    proxySelectorLabelsForSwxfll(swxfll.Name),
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: proxySelectorLabelsForSwxfll(swxfll.Name),
+			Ports: []corev1.ServicePort{{
+				Name:       "mcrouter",
+				Port:       proxyPortForSwxfll(swxfll),
+				TargetPort: intstr.FromString("mcrouter"),
+				Protocol:   corev1.ProtocolTCP,
+			}},
+		},
+	}
+
+	if err := ctrl.SetControllerReference(swxfll, svc, r.Scheme); err != nil {
+		return nil, err
+	}
+	return svc, nil
+}
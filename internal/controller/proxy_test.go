@@ -0,0 +1,97 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+var _ = Describe("mcrouter proxy", func() {
+	servers := []string{"10.0.0.1:11211", "10.0.0.2:11211"}
+
+	route := func(config string) interface{} {
+		var parsed struct {
+			Pools map[string]struct {
+				Servers []string `json:"servers"`
+			} `json:"pools"`
+			Route interface{} `json:"route"`
+		}
+		Expect(json.Unmarshal([]byte(config), &parsed)).To(Succeed())
+		Expect(parsed.Pools).To(HaveKeyWithValue("sessions", HaveField("Servers", Equal(servers))))
+		return parsed.Route
+	}
+
+	It("should render a sharded route", func() {
+		config, err := mcrouterConfig("sessions", servers, cachev1beta1.ProxyRouteSharded, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(route(config)).To(Equal("PoolRoute|sessions"))
+	})
+
+	It("should retry a sharded request on another instance with failover", func() {
+		config, err := mcrouterConfig("sessions", servers, cachev1beta1.ProxyRouteSharded, true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(route(config)).To(And(
+			HaveKeyWithValue("type", "FailoverRoute"),
+			HaveKeyWithValue("children", HaveLen(2)),
+		))
+	})
+
+	It("should write to all instances and read from one when replicated", func() {
+		config, err := mcrouterConfig("sessions", servers, cachev1beta1.ProxyRouteReplicated, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(route(config)).To(And(
+			HaveKeyWithValue("type", "OperationSelectorRoute"),
+			HaveKeyWithValue("default_policy", "AllSyncRoute|Pool|sessions"),
+			HaveKeyWithValue("operation_policies", HaveKeyWithValue("get", "LatestRoute|Pool|sessions")),
+		))
+	})
+
+	It("should keep the proxy pods out of the instance selector and roll them on config changes", func() {
+		Expect(os.Setenv("SWXFLL_MCROUTER_IMAGE", "example.com/mcrouter:default")).To(Succeed())
+		DeferCleanup(os.Unsetenv, "SWXFLL_MCROUTER_IMAGE")
+
+		scheme := runtime.NewScheme()
+		Expect(cachev1beta1.AddToScheme(scheme)).To(Succeed())
+		r := &SwxfllReconciler{Scheme: scheme}
+		swxfll := &cachev1beta1.Swxfll{
+			ObjectMeta: metav1.ObjectMeta{Name: "sessions", Namespace: "cache"},
+			Spec:       cachev1beta1.SwxfllSpec{Proxy: &cachev1beta1.ProxySpec{Enabled: true, Port: 5001}},
+		}
+
+		dep, err := r.proxyDeploymentForSwxfll(swxfll, "a")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Name).To(Equal("sessions-mcrouter"))
+		Expect(dep.Spec.Template.Spec.Containers[0].Image).To(Equal("example.com/mcrouter:default"))
+		Expect(dep.Spec.Template.Spec.Containers[0].Args).To(ContainElement("--port=5001"))
+		instances := labels.SelectorFromSet(selectorLabelsForSwxfll("sessions"))
+		Expect(instances.Matches(labels.Set(dep.Spec.Template.Labels))).To(BeFalse())
+
+		changed, err := r.proxyDeploymentForSwxfll(swxfll, "b")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed.Spec.Template.Annotations[proxyConfigHashAnnotation]).
+			NotTo(Equal(dep.Spec.Template.Annotations[proxyConfigHashAnnotation]))
+	})
+})
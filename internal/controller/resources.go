@@ -0,0 +1,77 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+// defaultOverheadPercent 是 Auto 模式下在 memcached 内存之外额外预留的内存比例
+const defaultOverheadPercent = 25
+
+// resourcesForSwxfll 返回 memcached 容器的资源配置。
+// Auto 模式（默认）下内存的 request 和 limit 相同，都等于 memoryMB 加上 OverheadPercent，
+// 这样 Pod 不再是 BestEffort，缓存用满配置的内存时也不会被 OOM kill。
+func resourcesForSwxfll(swxfll *cachev1beta1.Swxfll) corev1.ResourceRequirements {
+	var spec *cachev1beta1.ResourcesSpec
+	if swxfll.Spec.Scheduling != nil {
+		spec = swxfll.Spec.Scheduling.Resources
+	}
+	if spec == nil {
+		spec = &cachev1beta1.ResourcesSpec{}
+	}
+
+	requirements := corev1.ResourceRequirements{
+		Requests: copyResourceList(spec.Requests),
+		Limits:   copyResourceList(spec.Limits),
+	}
+	if spec.Mode == cachev1beta1.ResourcesModeManual {
+		return requirements
+	}
+
+	overhead := int64(defaultOverheadPercent)
+	if spec.OverheadPercent != nil {
+		overhead = int64(*spec.OverheadPercent)
+	}
+	// 按 MiB 向上取整
+	memoryMiB := (int64(memoryMBForSwxfll(swxfll))*(100+overhead) + 99) / 100
+	memory := *resource.NewQuantity(memoryMiB<<20, resource.BinarySI)
+
+	if requirements.Requests == nil {
+		requirements.Requests = corev1.ResourceList{}
+	}
+	if requirements.Limits == nil {
+		requirements.Limits = corev1.ResourceList{}
+	}
+	requirements.Requests[corev1.ResourceMemory] = memory
+	requirements.Limits[corev1.ResourceMemory] = memory
+	return requirements
+}
+
+func copyResourceList(list corev1.ResourceList) corev1.ResourceList {
+	if list == nil {
+		return nil
+	}
+	out := make(corev1.ResourceList, len(list))
+	for name, quantity := range list {
+		out[name] = quantity.DeepCopy()
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+var _ = Describe("resourcesForSwxfll", func() {
+	It("should derive a guaranteed memory size from the default cache size", func() {
+		res := resourcesForSwxfll(&cachev1beta1.Swxfll{})
+		Expect(res.Requests.Memory().String()).To(Equal("80Mi"))
+		Expect(res.Limits.Memory().String()).To(Equal("80Mi"))
+	})
+
+	It("should apply the configured overhead and keep cpu settings in Auto mode", func() {
+		res := resourcesForSwxfll(&cachev1beta1.Swxfll{Spec: cachev1beta1.SwxfllSpec{
+			Memcached: &cachev1beta1.MemcachedSpec{MemoryMB: 1024},
+			Scheduling: &cachev1beta1.SchedulingSpec{
+				Resources: &cachev1beta1.ResourcesSpec{
+					OverheadPercent: ptrTo(int32(10)),
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("250m"),
+						corev1.ResourceMemory: resource.MustParse("1Mi"),
+					},
+				},
+			},
+		}})
+		Expect(res.Requests.Memory().String()).To(Equal("1127Mi"))
+		Expect(res.Limits.Memory().String()).To(Equal("1127Mi"))
+		Expect(res.Requests.Cpu().String()).To(Equal("250m"))
+	})
+
+	It("should use the requirements as-is in Manual mode", func() {
+		res := resourcesForSwxfll(&cachev1beta1.Swxfll{Spec: cachev1beta1.SwxfllSpec{
+			Scheduling: &cachev1beta1.SchedulingSpec{
+				Resources: &cachev1beta1.ResourcesSpec{
+					Mode:   cachev1beta1.ResourcesModeManual,
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+				},
+			},
+		}})
+		Expect(res.Requests).To(BeEmpty())
+		Expect(res.Limits.Memory().String()).To(Equal("512Mi"))
+	})
+})
@@ -0,0 +1,134 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// progressDeadlineExceededReason 是 Deployment 在 progressDeadlineSeconds 内没有完成滚动更新时
+// Progressing 条件的 reason
+const progressDeadlineExceededReason = "ProgressDeadlineExceeded"
+
+// workloadRollout 是 Deployment 或 StatefulSet 滚动更新的进度
+type workloadRollout struct {
+	kind string
+	name string
+	// replicas 是期望的副本数
+	replicas int32
+	// observed 表示工作负载的控制器是否已经处理了最新的 generation
+	observed bool
+	// current 是包括旧模板在内的全部副本数
+	current   int32
+	updated   int32
+	ready     int32
+	available int32
+	// deadlineExceeded 表示 Deployment 控制器报告滚动更新超过了 progressDeadlineSeconds，StatefulSet 没有这一机制
+	deadlineExceeded bool
+}
+
+// rolloutForWorkload 从 Deployment 或 StatefulSet 的状态中读取滚动更新的进度
+func rolloutForWorkload(obj client.Object) workloadRollout {
+	rollout := workloadRollout{name: obj.GetName(), replicas: 1}
+	switch w := obj.(type) {
+	case *appsv1.Deployment:
+		rollout.kind = "Deployment"
+		if w.Spec.Replicas != nil {
+			rollout.replicas = *w.Spec.Replicas
+		}
+		rollout.observed = w.Status.ObservedGeneration >= w.Generation
+		rollout.current = w.Status.Replicas
+		rollout.updated = w.Status.UpdatedReplicas
+		rollout.ready = w.Status.ReadyReplicas
+		rollout.available = w.Status.AvailableReplicas
+		rollout.deadlineExceeded = progressDeadlineExceeded(w)
+	case *appsv1.StatefulSet:
+		rollout.kind = "StatefulSet"
+		if w.Spec.Replicas != nil {
+			rollout.replicas = *w.Spec.Replicas
+		}
+		rollout.observed = w.Status.ObservedGeneration >= w.Generation
+		rollout.current = w.Status.Replicas
+		rollout.updated = w.Status.UpdatedReplicas
+		rollout.ready = w.Status.ReadyReplicas
+		rollout.available = w.Status.AvailableReplicas
+	}
+	return rollout
+}
+
+// complete 判断最新模板是否已经滚动更新到全部副本并且可用
+func (r workloadRollout) complete() bool {
+	return r.observed &&
+		r.updated == r.replicas &&
+		r.current == r.replicas &&
+		r.available == r.replicas
+}
+
+// rolloutConditions 根据 Deployment 或 StatefulSet 自身的状态计算 swxfll 的 Available 和 Progressing 条件。
+// 只有最新模板的副本全部更新并且可用时 Available 才为 True，崩溃重启的 Pod 不会被计为可用；
+// 滚动更新进行中时 Progressing 为 True，完成或超过 progressDeadlineSeconds 时为 False。
+func rolloutConditions(obj client.Object) (available, progressing metav1.Condition) {
+	r := rolloutForWorkload(obj)
+	status := fmt.Sprintf("%d of %d replicas updated, %d available", r.updated, r.replicas, r.available)
+
+	switch {
+	case r.observed && r.updated >= r.replicas && r.available >= r.replicas:
+		available = metav1.Condition{Type: typeAvailableSwxfll, Status: metav1.ConditionTrue,
+			Reason:  "ReplicasAvailable",
+			Message: fmt.Sprintf("All %d replicas of %s %s are updated and available", r.replicas, r.kind, r.name)}
+	case r.deadlineExceeded:
+		available = metav1.Condition{Type: typeAvailableSwxfll, Status: metav1.ConditionFalse,
+			Reason:  progressDeadlineExceededReason,
+			Message: fmt.Sprintf("%s %s exceeded its progress deadline: %s", r.kind, r.name, status)}
+	default:
+		available = metav1.Condition{Type: typeAvailableSwxfll, Status: metav1.ConditionFalse,
+			Reason:  "ReplicasUnavailable",
+			Message: fmt.Sprintf("Waiting for %s %s: %s", r.kind, r.name, status)}
+	}
+
+	switch {
+	case r.deadlineExceeded:
+		progressing = metav1.Condition{Type: typeProgressingSwxfll, Status: metav1.ConditionFalse,
+			Reason:  progressDeadlineExceededReason,
+			Message: fmt.Sprintf("%s %s exceeded its progress deadline: %s", r.kind, r.name, status)}
+	case r.complete():
+		progressing = metav1.Condition{Type: typeProgressingSwxfll, Status: metav1.ConditionFalse,
+			Reason:  "RolloutComplete",
+			Message: fmt.Sprintf("%s %s has successfully rolled out", r.kind, r.name)}
+	default:
+		progressing = metav1.Condition{Type: typeProgressingSwxfll, Status: metav1.ConditionTrue,
+			Reason:  "RollingOut",
+			Message: fmt.Sprintf("Rolling out %s %s: %s", r.kind, r.name, status)}
+	}
+
+	return available, progressing
+}
+
+// progressDeadlineExceeded 判断 Deployment 控制器是否已经报告滚动更新超过了 progressDeadlineSeconds
+func progressDeadlineExceeded(dep *appsv1.Deployment) bool {
+	for _, cond := range dep.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing {
+			return cond.Status == corev1.ConditionFalse && cond.Reason == progressDeadlineExceededReason
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("rolloutConditions", func() {
+	newDeployment := func(updated, available int32) *appsv1.Deployment {
+		replicas := int32(3)
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache", Generation: 2},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status: appsv1.DeploymentStatus{
+				ObservedGeneration: 2,
+				Replicas:           3,
+				UpdatedReplicas:    updated,
+				AvailableReplicas:  available,
+			},
+		}
+	}
+
+	It("should be available and done once every replica is updated and available", func() {
+		available, progressing := rolloutConditions(newDeployment(3, 3))
+		Expect(available.Status).To(Equal(metav1.ConditionTrue))
+		Expect(progressing.Status).To(Equal(metav1.ConditionFalse))
+		Expect(progressing.Reason).To(Equal("RolloutComplete"))
+	})
+
+	It("should not count crash-looping replicas as available", func() {
+		available, progressing := rolloutConditions(newDeployment(3, 1))
+		Expect(available.Status).To(Equal(metav1.ConditionFalse))
+		Expect(available.Message).To(ContainSubstring("3 of 3 replicas updated, 1 available"))
+		Expect(progressing.Status).To(Equal(metav1.ConditionTrue))
+	})
+
+	It("should wait for the Deployment controller to observe the latest generation", func() {
+		dep := newDeployment(3, 3)
+		dep.Generation = 3
+		available, progressing := rolloutConditions(dep)
+		Expect(available.Status).To(Equal(metav1.ConditionFalse))
+		Expect(progressing.Status).To(Equal(metav1.ConditionTrue))
+	})
+
+	It("should report a rollout stuck past its progress deadline", func() {
+		dep := newDeployment(1, 2)
+		dep.Status.Conditions = []appsv1.DeploymentCondition{{
+			Type:   appsv1.DeploymentProgressing,
+			Status: corev1.ConditionFalse,
+			Reason: progressDeadlineExceededReason,
+		}}
+		available, progressing := rolloutConditions(dep)
+		Expect(available.Status).To(Equal(metav1.ConditionFalse))
+		Expect(available.Reason).To(Equal(progressDeadlineExceededReason))
+		Expect(progressing.Status).To(Equal(metav1.ConditionFalse))
+		Expect(progressing.Reason).To(Equal(progressDeadlineExceededReason))
+	})
+
+	It("should follow the rollout of a StatefulSet", func() {
+		replicas := int32(3)
+		sts := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache", Generation: 1},
+			Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+			Status: appsv1.StatefulSetStatus{
+				ObservedGeneration: 1,
+				Replicas:           3,
+				UpdatedReplicas:    2,
+				AvailableReplicas:  3,
+			},
+		}
+		available, progressing := rolloutConditions(sts)
+		Expect(available.Status).To(Equal(metav1.ConditionFalse))
+		Expect(progressing.Message).To(ContainSubstring("StatefulSet cache"))
+		Expect(rolloutForWorkload(sts).complete()).To(BeFalse())
+
+		sts.Status.UpdatedReplicas = 3
+		available, progressing = rolloutConditions(sts)
+		Expect(available.Status).To(Equal(metav1.ConditionTrue))
+		Expect(progressing.Reason).To(Equal("RolloutComplete"))
+		Expect(rolloutForWorkload(sts).complete()).To(BeTrue())
+	})
+})
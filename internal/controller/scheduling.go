@@ -0,0 +1,107 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+const (
+	// nodeAntiAffinityWeight 和 zoneAntiAffinityWeight 是默认 Pod 反亲和性的权重，
+	// 优先把同一个 Swxfll 的实例分散到不同节点，其次分散到不同可用区
+	nodeAntiAffinityWeight = 100
+	zoneAntiAffinityWeight = 50
+)
+
+// schedulingSpecForSwxfll 返回 Spec.Scheduling，未设置时返回空的 SchedulingSpec
+func schedulingSpecForSwxfll(swxfll *cachev1beta1.Swxfll) *cachev1beta1.SchedulingSpec {
+	if swxfll.Spec.Scheduling == nil {
+		return &cachev1beta1.SchedulingSpec{}
+	}
+	return swxfll.Spec.Scheduling
+}
+
+// affinityForSwxfll 返回实例 Pod 的亲和性配置。
+// 未设置 podAntiAffinity 时添加默认的软反亲和性，避免同一个缓存的实例位于同一个故障域，
+// 一个节点或可用区故障时只会丢失部分 key。使用软约束是为了节点或可用区不足时实例仍然可以被调度。
+func affinityForSwxfll(swxfll *cachev1beta1.Swxfll) *corev1.Affinity {
+	affinity := &corev1.Affinity{}
+	if a := schedulingSpecForSwxfll(swxfll).Affinity; a != nil {
+		affinity = a.DeepCopy()
+	}
+	if affinity.PodAntiAffinity == nil {
+		affinity.PodAntiAffinity = defaultPodAntiAffinity(swxfll.Name)
+	}
+	return affinity
+}
+
+// defaultPodAntiAffinity 返回让 Pod 尽量不与同一个 Swxfll 的其他实例位于同一节点和同一可用区的反亲和性
+func defaultPodAntiAffinity(name string) *corev1.PodAntiAffinity {
+	term := func(weight int32, topologyKey string) corev1.WeightedPodAffinityTerm {
+		return corev1.WeightedPodAffinityTerm{
+			Weight: weight,
+			PodAffinityTerm: corev1.PodAffinityTerm{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: selectorLabelsForSwxfll(name)},
+				TopologyKey:   topologyKey,
+			},
+		}
+	}
+	return &corev1.PodAntiAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+			term(nodeAntiAffinityWeight, corev1.LabelHostname),
+			term(zoneAntiAffinityWeight, corev1.LabelTopologyZone),
+		},
+	}
+}
+
+// tolerationsForSwxfll 返回实例 Pod 的容忍度
+func tolerationsForSwxfll(swxfll *cachev1beta1.Swxfll) []corev1.Toleration {
+	var tolerations []corev1.Toleration
+	for _, t := range schedulingSpecForSwxfll(swxfll).Tolerations {
+		tolerations = append(tolerations, *t.DeepCopy())
+	}
+	return tolerations
+}
+
+// nodeSelectorForSwxfll 返回实例 Pod 的节点选择器
+func nodeSelectorForSwxfll(swxfll *cachev1beta1.Swxfll) map[string]string {
+	spec := schedulingSpecForSwxfll(swxfll)
+	if len(spec.NodeSelector) == 0 {
+		return nil
+	}
+	nodeSelector := make(map[string]string, len(spec.NodeSelector))
+	for k, v := range spec.NodeSelector {
+		nodeSelector[k] = v
+	}
+	return nodeSelector
+}
+
+// topologySpreadConstraintsForSwxfll 返回实例 Pod 的拓扑分布约束，未设置 labelSelector 的约束选择同一个 Swxfll 的实例
+func topologySpreadConstraintsForSwxfll(swxfll *cachev1beta1.Swxfll) []corev1.TopologySpreadConstraint {
+	var constraints []corev1.TopologySpreadConstraint
+	for _, c := range schedulingSpecForSwxfll(swxfll).TopologySpreadConstraints {
+		constraint := c.DeepCopy()
+		if constraint.LabelSelector == nil {
+			constraint.LabelSelector = &metav1.LabelSelector{MatchLabels: selectorLabelsForSwxfll(swxfll.Name)}
+		}
+		constraints = append(constraints, *constraint)
+	}
+	return constraints
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+var _ = Describe("Scheduling", func() {
+	var swxfll *cachev1beta1.Swxfll
+
+	BeforeEach(func() {
+		swxfll = &cachev1beta1.Swxfll{
+			ObjectMeta: metav1.ObjectMeta{Name: "sessions", Namespace: "cache"},
+		}
+	})
+
+	It("should prefer spreading the instances across nodes and zones by default", func() {
+		affinity := affinityForSwxfll(swxfll)
+		Expect(affinity.NodeAffinity).To(BeNil())
+		Expect(affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(BeEmpty())
+
+		terms := affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+		Expect(terms).To(HaveLen(2))
+		Expect(terms[0].PodAffinityTerm.TopologyKey).To(Equal(corev1.LabelHostname))
+		Expect(terms[1].PodAffinityTerm.TopologyKey).To(Equal(corev1.LabelTopologyZone))
+		Expect(terms[0].Weight).To(BeNumerically(">", terms[1].Weight))
+		for _, term := range terms {
+			Expect(term.PodAffinityTerm.LabelSelector.MatchLabels).To(Equal(selectorLabelsForSwxfll("sessions")))
+		}
+
+		Expect(nodeSelectorForSwxfll(swxfll)).To(BeNil())
+		Expect(tolerationsForSwxfll(swxfll)).To(BeNil())
+		Expect(topologySpreadConstraintsForSwxfll(swxfll)).To(BeNil())
+	})
+
+	It("should keep the default anti-affinity next to a node affinity", func() {
+		nodeAffinity := &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: "kubernetes.io/arch", Operator: corev1.NodeSelectorOpIn, Values: []string{"amd64", "arm64"}},
+					},
+				}},
+			},
+		}
+		swxfll.Spec.Scheduling = &cachev1beta1.SchedulingSpec{Affinity: &corev1.Affinity{NodeAffinity: nodeAffinity}}
+
+		affinity := affinityForSwxfll(swxfll)
+		Expect(affinity.NodeAffinity).To(Equal(nodeAffinity))
+		Expect(affinity.PodAntiAffinity).To(Equal(defaultPodAntiAffinity("sessions")))
+		Expect(swxfll.Spec.Scheduling.Affinity.PodAntiAffinity).To(BeNil())
+	})
+
+	It("should replace the default anti-affinity with the configured one", func() {
+		swxfll.Spec.Scheduling = &cachev1beta1.SchedulingSpec{
+			Affinity: &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{}},
+		}
+		Expect(affinityForSwxfll(swxfll).PodAntiAffinity).To(Equal(&corev1.PodAntiAffinity{}))
+	})
+
+	It("should select the instances in topology spread constraints without a selector", func() {
+		other := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+		swxfll.Spec.Scheduling = &cachev1beta1.SchedulingSpec{
+			TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+				{MaxSkew: 1, TopologyKey: corev1.LabelTopologyZone, WhenUnsatisfiable: corev1.DoNotSchedule},
+				{MaxSkew: 2, TopologyKey: corev1.LabelHostname, WhenUnsatisfiable: corev1.ScheduleAnyway, LabelSelector: other},
+			},
+		}
+
+		constraints := topologySpreadConstraintsForSwxfll(swxfll)
+		Expect(constraints).To(HaveLen(2))
+		Expect(constraints[0].LabelSelector.MatchLabels).To(Equal(selectorLabelsForSwxfll("sessions")))
+		Expect(constraints[1].LabelSelector).To(Equal(other))
+		Expect(swxfll.Spec.Scheduling.TopologySpreadConstraints[0].LabelSelector).To(BeNil())
+	})
+})
@@ -0,0 +1,139 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+const (
+	// serverListHashAnnotation 记录 ConfigMap 中服务器列表的哈希，便于在不解析内容的情况下判断成员是否变化
+	serverListHashAnnotation = "cache.swxfll.com/servers-hash"
+
+	// ConfigMap 中各个格式的键
+	serverListPlainKey    = "servers"
+	serverListJSONKey     = "servers.json"
+	serverListMcrouterKey = "mcrouter.json"
+)
+
+// serverListConfigMapName 返回发布服务器列表的 ConfigMap 的名称
+func serverListConfigMapName(name string) string {
+	return name + "-servers"
+}
+
+// podServers 返回客户端连接 Pod 使用的 host:port，按字典序排序。
+// StatefulSet 的 Pod 设置了 hostname 和 subdomain，使用 headless Service 下稳定的 DNS 名称，
+// 这样 Pod 被替换后一致性哈希环保持不变；其他 Pod 使用 IP。
+func podServers(pods []corev1.Pod, port int32) []string {
+	var servers []string
+	for i := range pods {
+		pod := &pods[i]
+		host := pod.Status.PodIP
+		if pod.Spec.Hostname != "" && pod.Spec.Subdomain != "" {
+			host = pod.Spec.Hostname + "." + pod.Spec.Subdomain + "." + pod.Namespace + ".svc"
+		}
+		servers = append(servers, net.JoinHostPort(host, strconv.Itoa(int(port))))
+	}
+	sort.Strings(servers)
+	return servers
+}
+
+// serverListHash 返回纯文本服务器列表的 SHA-256
+func serverListHash(servers []string) string {
+	sum := sha256.Sum256([]byte(plainServerList(servers)))
+	return hex.EncodeToString(sum[:])
+}
+
+// plainServerList 返回每行一个 host:port 的服务器列表
+func plainServerList(servers []string) string {
+	if len(servers) == 0 {
+		return ""
+	}
+	return strings.Join(servers, "\n") + "\n"
+}
+
+// reconcileServerList 将就绪 Pod 的服务器列表写入 swxfll 拥有的 ConfigMap 和状态。
+// Pod 的就绪状态变化会触发调和，因此列表始终跟随成员变化。
+func (r *SwxfllReconciler) reconcileServerList(ctx context.Context, swxfll *cachev1beta1.Swxfll, pods []corev1.Pod) error {
+	servers := podServers(pods, portForSwxfll(swxfll))
+	cm, err := r.serverListConfigMapForSwxfll(swxfll, servers)
+	if err != nil {
+		return err
+	}
+	if err := r.apply(ctx, cm); err != nil {
+		return err
+	}
+
+	swxfll.Status.ServerList = &cachev1beta1.ServerListStatus{
+		ConfigMap: cm.Name,
+		Servers:   servers,
+		Hash:      serverListHash(servers),
+	}
+	return nil
+}
+
+// serverListConfigMapForSwxfll 返回以纯文本、JSON 和 mcrouter 配置三种格式保存服务器列表的 ConfigMap 对象
+func (r *SwxfllReconciler) serverListConfigMapForSwxfll(swxfll *cachev1beta1.Swxfll, servers []string) (*corev1.ConfigMap, error) {
+	list := servers
+	if list == nil {
+		list = []string{}
+	}
+	serversJSON, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+	mcrouter, err := mcrouterConfig(swxfll.Name, servers, cachev1beta1.ProxyRouteSharded, false)
+	if err != nil {
+		return nil, err
+	}
+
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        serverListConfigMapName(swxfll.Name),
+			Namespace:   swxfll.Namespace,
+			Labels:      selectorLabelsForSwxfll(swxfll.Name),
+			Annotations: map[string]string{serverListHashAnnotation: serverListHash(servers)},
+		},
+		Data: map[string]string{
+			serverListPlainKey:    plainServerList(servers),
+			serverListJSONKey:     string(serversJSON),
+			serverListMcrouterKey: mcrouter,
+		},
+	}
+
+	if err := ctrl.SetControllerReference(swxfll, cm, r.Scheme); err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+var _ = Describe("Server list", func() {
+	newPod := func(name, ip string, stateful bool) corev1.Pod {
+		pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "cache"}}
+		pod.Status.PodIP = ip
+		if stateful {
+			pod.Spec.Hostname = name
+			pod.Spec.Subdomain = "sessions-headless"
+		}
+		return pod
+	}
+
+	It("should list pods by IP and StatefulSet pods by their stable DNS name, sorted", func() {
+		Expect(podServers([]corev1.Pod{
+			newPod("b", "10.0.0.2", false),
+			newPod("a", "10.0.0.1", false),
+		}, 11211)).To(Equal([]string{"10.0.0.1:11211", "10.0.0.2:11211"}))
+
+		Expect(podServers([]corev1.Pod{
+			newPod("sessions-1", "10.0.0.1", true),
+			newPod("sessions-0", "10.0.0.2", true),
+		}, 11211)).To(Equal([]string{
+			"sessions-0.sessions-headless.cache.svc:11211",
+			"sessions-1.sessions-headless.cache.svc:11211",
+		}))
+	})
+
+	It("should change the hash only when the membership changes", func() {
+		hash := serverListHash([]string{"10.0.0.1:11211", "10.0.0.2:11211"})
+		Expect(hash).To(HaveLen(64))
+		Expect(serverListHash([]string{"10.0.0.1:11211", "10.0.0.2:11211"})).To(Equal(hash))
+		Expect(serverListHash([]string{"10.0.0.1:11211"})).NotTo(Equal(hash))
+	})
+
+	It("should render the list in every format", func() {
+		scheme := runtime.NewScheme()
+		Expect(cachev1beta1.AddToScheme(scheme)).To(Succeed())
+		r := &SwxfllReconciler{Scheme: scheme}
+		swxfll := &cachev1beta1.Swxfll{ObjectMeta: metav1.ObjectMeta{Name: "sessions", Namespace: "cache"}}
+		servers := []string{"10.0.0.1:11211", "10.0.0.2:11211"}
+
+		cm, err := r.serverListConfigMapForSwxfll(swxfll, servers)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cm.Name).To(Equal("sessions-servers"))
+		Expect(cm.OwnerReferences).To(HaveLen(1))
+		Expect(cm.Data[serverListPlainKey]).To(Equal("10.0.0.1:11211\n10.0.0.2:11211\n"))
+		Expect(cm.Data[serverListJSONKey]).To(Equal(`["10.0.0.1:11211","10.0.0.2:11211"]`))
+		Expect(cm.Annotations).To(HaveKeyWithValue(serverListHashAnnotation, serverListHash(servers)))
+
+		var mcrouter struct {
+			Pools map[string]struct {
+				Servers []string `json:"servers"`
+			} `json:"pools"`
+			Route string `json:"route"`
+		}
+		Expect(json.Unmarshal([]byte(cm.Data[serverListMcrouterKey]), &mcrouter)).To(Succeed())
+		Expect(mcrouter.Route).To(Equal("PoolRoute|sessions"))
+		Expect(mcrouter.Pools["sessions"].Servers).To(Equal(servers))
+
+		By("rendering an empty list before any pod is ready")
+		cm, err = r.serverListConfigMapForSwxfll(swxfll, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cm.Data[serverListPlainKey]).To(BeEmpty())
+		Expect(cm.Data[serverListJSONKey]).To(Equal("[]"))
+	})
+})
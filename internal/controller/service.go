@@ -0,0 +1,140 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+// headlessServiceName 返回用于逐个发现 Pod 的 headless Service 的名称
+func headlessServiceName(name string) string {
+	return name + "-headless"
+}
+
+// portForSwxfll 返回 memcached 监听的容器端口，未设置 Spec.Networking.Port 时使用默认端口
+func portForSwxfll(swxfll *cachev1beta1.Swxfll) int32 {
+	if networking := swxfll.Spec.Networking; networking != nil && networking.Port != 0 {
+		return networking.Port
+	}
+	return cachev1beta1.DefaultContainerPort
+}
+
+// serviceSpecForSwxfll 返回 Spec.Networking.Service，未设置时返回 nil
+func serviceSpecForSwxfll(swxfll *cachev1beta1.Swxfll) *cachev1beta1.ServiceSpec {
+	if swxfll.Spec.Networking == nil {
+		return nil
+	}
+	return swxfll.Spec.Networking.Service
+}
+
+// reconcileServices 通过 server-side apply 创建或更新 swxfll 拥有的 ClusterIP Service 和 headless Service
+func (r *SwxfllReconciler) reconcileServices(ctx context.Context, swxfll *cachev1beta1.Swxfll) error {
+	svc, err := r.serviceForSwxfll(swxfll)
+	if err != nil {
+		return err
+	}
+	if err := r.apply(ctx, svc); err != nil {
+		return err
+	}
+
+	headless, err := r.headlessServiceForSwxfll(swxfll)
+	if err != nil {
+		return err
+	}
+	return r.apply(ctx, headless)
+}
+
+// serviceForSwxfll 返回面向客户端的 Service 对象，类型、注解和端口由 Spec.Networking.Service 决定
+func (r *SwxfllReconciler) serviceForSwxfll(swxfll *cachev1beta1.Swxfll) (*corev1.Service, error) {
+	serviceType := corev1.ServiceTypeClusterIP
+	var nodePort int32
+	if spec := serviceSpecForSwxfll(swxfll); spec != nil {
+		if spec.Type != "" {
+			serviceType = spec.Type
+		}
+		// 只有 NodePort 和 LoadBalancer 类型的 Service 才允许设置 nodePort
+		if serviceType != corev1.ServiceTypeClusterIP {
+			nodePort = spec.NodePort
+		}
+	}
+
+	svc := newServiceForSwxfll(swxfll, swxfll.Name)
+	svc.Spec.Type = serviceType
+	svc.Spec.Ports[0].NodePort = nodePort
+
+	if err := ctrl.SetControllerReference(swxfll, svc, r.Scheme); err != nil {
+		return nil, err
+	}
+	return svc, nil
+}
+
+// headlessServiceForSwxfll 返回 headless Service 对象，客户端可以通过它的 DNS 记录逐个发现 Pod
+func (r *SwxfllReconciler) headlessServiceForSwxfll(swxfll *cachev1beta1.Swxfll) (*corev1.Service, error) {
+	svc := newServiceForSwxfll(swxfll, headlessServiceName(swxfll.Name))
+	svc.Spec.Type = corev1.ServiceTypeClusterIP
+	svc.Spec.ClusterIP = corev1.ClusterIPNone
+	// exporter 的指标端口只加在 headless Service 上，关闭监控时 apply 会移除该端口
+	if monitoringEnabled(swxfll) {
+		svc.Spec.Ports = append(svc.Spec.Ports, metricsServicePort())
+	}
+
+	if err := ctrl.SetControllerReference(swxfll, svc, r.Scheme); err != nil {
+		return nil, err
+	}
+	return svc, nil
+}
+
+// newServiceForSwxfll 返回两个 Service 共用的部分：标签、注解、selector 以及指向容器端口的端口定义
+func newServiceForSwxfll(swxfll *cachev1beta1.Swxfll, name string) *corev1.Service {
+	port := portForSwxfll(swxfll)
+	var annotations map[string]string
+	if spec := serviceSpecForSwxfll(swxfll); spec != nil {
+		if spec.Port != 0 {
+			port = spec.Port
+		}
+		annotations = spec.Annotations
+	}
+
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   swxfll.Namespace,
+			Labels:      selectorLabelsForSwxfll(swxfll.Name),
+			Annotations: annotations,
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: selectorLabelsForSwxfll(swxfll.Name),
+			Ports: []corev1.ServicePort{{
+				Name:       "swxfll",
+				Port:       port,
+				TargetPort: intstr.FromString("swxfll"),
+				Protocol:   corev1.ProtocolTCP,
+			}},
+		},
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+// readyPodsForSwxfll 列出 swxfll 所有就绪、未被删除并且已经分配了 IP 的 Pod
+func (r *SwxfllReconciler) readyPodsForSwxfll(ctx context.Context, swxfll *cachev1beta1.Swxfll) ([]corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(swxfll.Namespace),
+		client.MatchingLabels(selectorLabelsForSwxfll(swxfll.Name))); err != nil {
+		return nil, err
+	}
+
+	var ready []corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil || pod.Status.PodIP == "" || !podReady(pod) {
+			continue
+		}
+		ready = append(ready, *pod)
+	}
+	return ready, nil
+}
+
+// podEndpoints 返回 Pod 的 ip:port，按字典序排序，这样 Pod 列表的顺序变化不会导致状态更新
+func podEndpoints(pods []corev1.Pod, port int32) []string {
+	var endpoints []string
+	for i := range pods {
+		endpoints = append(endpoints, net.JoinHostPort(pods[i].Status.PodIP, strconv.Itoa(int(port))))
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+// podReady 判断 Pod 的 Ready 条件是否为 True
+func podReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// swxfllForPod 将 Pod 的事件映射为所属 Swxfll 的调和请求。
+// Pod 由 ReplicaSet 拥有，无法通过 Owns 监听，只能根据 selector 标签找到对应的 Swxfll。
+func swxfllForPod(_ context.Context, obj client.Object) []reconcile.Request {
+	name := obj.GetLabels()["app.kubernetes.io/instance"]
+	if name == "" || !containsLabels(obj.GetLabels(), selectorLabelsForSwxfll(name)) {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name, Namespace: obj.GetNamespace()}}}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("swxfllForPod", func() {
+	newPod := func(labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "cache", Labels: labels}}
+	}
+
+	It("should map a pod to the Swxfll named by its instance label", func() {
+		labels := labelsForSwxfll("sessions", "1.6.23")
+		Expect(swxfllForPod(context.Background(), newPod(labels))).To(Equal([]reconcile.Request{
+			{NamespacedName: types.NamespacedName{Name: "sessions", Namespace: "cache"}},
+		}))
+	})
+
+	It("should ignore pods not created for a Swxfll", func() {
+		Expect(swxfllForPod(context.Background(), newPod(nil))).To(BeEmpty())
+		Expect(swxfllForPod(context.Background(), newPod(map[string]string{
+			"app.kubernetes.io/instance": "sessions",
+			"app.kubernetes.io/name":     "redis",
+		}))).To(BeEmpty())
+	})
+})
+
+var _ = Describe("podReady", func() {
+	It("should only report pods with a true Ready condition", func() {
+		pod := &corev1.Pod{}
+		Expect(podReady(pod)).To(BeFalse())
+
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}}
+		Expect(podReady(pod)).To(BeFalse())
+
+		pod.Status.Conditions[0].Status = corev1.ConditionTrue
+		Expect(podReady(pod)).To(BeTrue())
+	})
+})
@@ -32,7 +32,7 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
-	cachev1alpha1 "github.com/swxfll/operator-sdk-demo/api/v1alpha1"
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -43,6 +43,9 @@ var cfg *rest.Config
 var k8sClient client.Client
 var testEnv *envtest.Environment
 
+// ptrTo 返回 v 的指针，用于构造测试对象中的可选字段
+func ptrTo[T any](v T) *T { return &v }
+
 func TestControllers(t *testing.T) {
 	RegisterFailHandler(Fail)
 
@@ -72,7 +75,7 @@ var _ = BeforeSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 	Expect(cfg).NotTo(BeNil())
 
-	err = cachev1alpha1.AddToScheme(scheme.Scheme)
+	err = cachev1beta1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
 	//+kubebuilder:scaffold:scheme
@@ -18,12 +18,16 @@ package controller
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	"os"
@@ -34,20 +38,31 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
-	cachev1alpha1 "github.com/swxfll/operator-sdk-demo/api/v1alpha1"
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
 )
 
 const swxfllFinalizer = "cache.swxfll.com/finalizer"
 
+// fieldManager 是 operator 通过 server-side apply 写入对象时使用的字段管理者名称
+const fieldManager = "swxfll-operator"
+
 // 用于管理状态条件的定义
 const (
-	// typeAvailableSwxfll 表示 Deployment 调和的状态
+	// typeAvailableSwxfll 表示 Deployment 调和的状态，只有全部副本都更新并且可用时才为 True
 	typeAvailableSwxfll = "Available"
-	// typeDegradedSwxfll 表示当自定义资源被删除并且必须执行 finalizer 操作时使用的状态。
+	// typeProgressingSwxfll 表示 Deployment 是否正在滚动更新
+	typeProgressingSwxfll = "Progressing"
+	// typeDegradedSwxfll 表示当自定义资源被删除并且必须执行 finalizer 操作时使用的状态，
+	// 也用于报告被拒绝的只修改了密码的凭据。
 	typeDegradedSwxfll = "Degraded"
+	// typeDriftedSwxfll 表示 Deployment 中由 operator 管理的字段是否被手工修改过
+	typeDriftedSwxfll = "Drifted"
 )
 
 // SwxfllReconciler 调和 Swxfll 对象
@@ -56,6 +71,11 @@ type SwxfllReconciler struct {
 	Scheme *runtime.Scheme
 	//  EventRecorder 知道如何代表 EventSource 记录事件
 	Recorder record.EventRecorder
+	// StatsInterval 是通过 memcached 文本协议采集统计信息并写入状态的周期，为 0 时不采集
+	StatsInterval time.Duration
+	// OperatorNamespace 是 operator 运行的命名空间，NetworkPolicy 通过它允许 operator 连接 memcached。
+	// 为空时（例如在集群外运行）不添加 operator 的规则。
+	OperatorNamespace string
 }
 
 //+kubebuilder:rbac:groups=cache.swxfll.com,resources=swxflls,verbs=get;list;watch;create;update;patch;delete
@@ -63,7 +83,14 @@ type SwxfllReconciler struct {
 //+kubebuilder:rbac:groups=cache.swxfll.com,resources=swxflls/finalizers,verbs=update
 //+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile 是 Kubernetes 主要调和循环的一部分，旨在将集群的当前状态移向期望的状态。
 // 控制器的调和循环必须是幂等的是至关重要的。通过遵循 Operator 模式，您将创建控制器，
@@ -81,7 +108,7 @@ func (r *SwxfllReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	// 目的是检查集群上是否已应用 Kind 为 Swxfll 的自定义资源
 	// 如果没有应用，则返回 nil 以停止调和过程
 	// 查找此调和请求的 swxfll 实例
-	swxfll := &cachev1alpha1.Swxfll{}
+	swxfll := &cachev1beta1.Swxfll{}
 	err := r.Get(ctx, req.NamespacedName, swxfll)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
@@ -100,7 +127,7 @@ func (r *SwxfllReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			Reason:  "Reconciling",
 			Message: "开Starting reconciliation",
 		})
-		if err = r.Status().Update(ctx, swxfll); err != nil {
+		if err = r.applyStatus(ctx, swxfll); err != nil {
 			log.Error(err, "无法更新 Swxfll 状态")
 			return ctrl.Result{}, err
 		}
@@ -147,7 +174,7 @@ func (r *SwxfllReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 					Reason:  "Finalizing",
 					Message: fmt.Sprintf("执行自定义资源的 finalizer 操作: %s", swxfll.Name)})
 
-			if err := r.Status().Update(ctx, swxfll); err != nil {
+			if err := r.applyStatus(ctx, swxfll); err != nil {
 				log.Error(err, "无法更新 swxfll 状态")
 				return ctrl.Result{}, err
 			}
@@ -174,7 +201,7 @@ func (r *SwxfllReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 					Reason:  "Finalizing",
 					Message: fmt.Sprintf("自定义资源 %s 的 finalizer 操作已成功完成", swxfll.Name)})
 
-			if err := r.Status().Update(ctx, swxfll); err != nil {
+			if err := r.applyStatus(ctx, swxfll); err != nil {
 				log.Error(err, "Failed to update swxfll status")
 				return ctrl.Result{}, err
 			}
@@ -194,106 +221,329 @@ func (r *SwxfllReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, nil
 	}
 
-	// 检查deployment是否已存在，如果不存在，则创建一个新的
-	found := &appsv1.Deployment{}
+	// 开启 TLS 时在创建工作负载之前准备好证书 Secret，Pod 启动时才能挂载它
+	tlsState, err := r.reconcileTLS(ctx, swxfll, time.Now())
+	if err != nil {
+		log.Error(err, "Failed to reconcile TLS certificates", "Swxfll.Namespace", swxfll.Namespace, "Swxfll.Name", swxfll.Name)
+		r.Recorder.Event(swxfll, "Warning", "TLSFailed",
+			fmt.Sprintf("Failed to reconcile TLS certificates for %s/%s: %s", swxfll.Namespace, swxfll.Name, err))
+		return ctrl.Result{}, err
+	}
+
+	// 开启认证时准备好凭据和 SASL 密码文件
+	credentials, err := r.reconcileAuth(ctx, swxfll, time.Now())
+	if err != nil {
+		log.Error(err, "Failed to reconcile credentials", "Swxfll.Namespace", swxfll.Namespace, "Swxfll.Name", swxfll.Name)
+		r.Recorder.Event(swxfll, "Warning", "AuthFailed",
+			fmt.Sprintf("Failed to reconcile credentials for %s/%s: %s", swxfll.Namespace, swxfll.Name, err))
+		return ctrl.Result{}, err
+	}
+
+	// 检查工作负载（根据 Spec.WorkloadType 为 Deployment 或 StatefulSet）是否已存在。
+	// 无论是否存在，期望的工作负载都会通过 server-side apply 写入，
+	// 这样 operator 只拥有 deploymentForSwxfll 渲染的字段，不会覆盖其他控制器设置的字段。
+	kind := string(workloadTypeForSwxfll(swxfll))
+	found := newWorkload(workloadTypeForSwxfll(swxfll))
 	err = r.Get(ctx, types.NamespacedName{
 		Name:      swxfll.Name,
 		Namespace: swxfll.Namespace,
 	}, found)
-	if err != nil && apierrors.IsNotFound(err) {
-		// Define a new deployment
-		dep, err := r.deploymentForSwxfll(swxfll)
-		if err != nil {
-			log.Error(err, "无法为 swxfll 定义新的 Deployment 资源")
+	if err != nil && !apierrors.IsNotFound(err) {
+		log.Error(err, "Failed to get workload", "kind", kind)
+		// Let's return the error for the reconciliation be re-trigged again
+		return ctrl.Result{}, err
+	}
+	exists := err == nil
 
-			// 以下实现将更新状态
-			meta.SetStatusCondition(&swxfll.Status.Conditions,
-				metav1.Condition{
-					Type:   typeAvailableSwxfll,
-					Status: metav1.ConditionFalse,
-					Reason: "Reconciling",
-					Message: fmt.Sprintf("Failed to create Deployment for the custom resource (%s): (%s)",
-						swxfll.Name, err)})
+	// 根据 deploymentForSwxfll 计算期望的工作负载，
+	// 用于检测并纠正对 operator 管理字段（镜像、参数、端口、标签、securityContext）的手工修改。
+	workload, err := r.workloadForSwxfll(swxfll)
+	if err != nil {
+		log.Error(err, "无法为 swxfll 定义期望的工作负载资源", "kind", kind)
+
+		// 以下实现将更新状态
+		meta.SetStatusCondition(&swxfll.Status.Conditions,
+			metav1.Condition{
+				Type:   typeAvailableSwxfll,
+				Status: metav1.ConditionFalse,
+				Reason: "Reconciling",
+				Message: fmt.Sprintf("Failed to create %s for the custom resource (%s): (%s)",
+					kind, swxfll.Name, err)})
+
+		if err := r.applyStatus(ctx, swxfll); err != nil {
+			log.Error(err, "Failed to update swxfll status")
+			return ctrl.Result{}, err
+		}
 
-			if err := r.Status().Update(ctx, swxfll); err != nil {
-				log.Error(err, "Failed to update swxfll status")
+		return ctrl.Result{}, err
+	}
+
+	var drifted []string
+	if exists {
+		// selector 等不可变字段与期望不一致时无法原地更新，只能删除后由下一次调和重新创建。
+		if workloadNeedsRecreate(workload, found) {
+			log.Info("工作负载的不可变字段与期望不一致，删除后重新创建",
+				"kind", kind, "Namespace", found.GetNamespace(), "Name", found.GetName())
+			r.Recorder.Event(swxfll, "Warning", "Recreating"+kind,
+				fmt.Sprintf("%s %s/%s has an outdated selector and will be recreated", kind, found.GetNamespace(), found.GetName()))
+			if err := r.Delete(ctx, found, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil &&
+				!apierrors.IsNotFound(err) {
+				log.Error(err, "Failed to delete workload",
+					"kind", kind, "Namespace", found.GetNamespace(), "Name", found.GetName())
 				return ctrl.Result{}, err
 			}
+			return ctrl.Result{Requeue: true}, nil
+		}
 
+		// 任何 operator 管理的字段与期望不一致时，通过 Event 报告漂移的字段，
+		// 随后的 apply 会强制将这些字段恢复为期望状态。
+		drifted = workloadDrift(workload, found)
+		if len(drifted) > 0 {
+			log.Info("检测到工作负载漂移，恢复为期望状态",
+				"kind", kind, "Namespace", found.GetNamespace(), "Name", found.GetName(), "fields", drifted)
+			r.Recorder.Event(swxfll, "Warning", "DriftDetected",
+				fmt.Sprintf("%s %s/%s drifted from the desired state: %s",
+					kind, found.GetNamespace(), found.GetName(), strings.Join(drifted, ", ")))
+		}
+	} else {
+		log.Info("Creating a new workload",
+			"kind", kind, "Namespace", workload.GetNamespace(), "Name", workload.GetName())
+	}
+
+	// CRD API 定义了 swxfll 类型，具有 swxfll.Size 字段
+	// 用于设置集群中所需状态的工作负载实例数量。
+	// 期望的工作负载中已经包含 Size，因此 apply 会同时确保它的大小与 Size spec 相同。
+	if err = r.apply(ctx, workload); err != nil {
+		log.Error(err, "Failed to apply workload",
+			"kind", kind, "Namespace", workload.GetNamespace(), "Name", workload.GetName())
+
+		// The following implementation will update the status
+		meta.SetStatusCondition(&swxfll.Status.Conditions, metav1.Condition{Type: typeAvailableSwxfll,
+			Status: metav1.ConditionFalse, Reason: "Reconciling",
+			Message: fmt.Sprintf("Failed to apply %s for the custom resource (%s): (%s)", kind, swxfll.Name, err)})
+
+		if err := r.applyStatus(ctx, swxfll); err != nil {
+			log.Error(err, "Failed to update swxfll status")
 			return ctrl.Result{}, err
 		}
 
-		log.Info("Creating a new Deployment",
-			"Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
-		if err = r.Create(ctx, dep); err != nil {
-			log.Error(err, "Failed to create new Deployment",
-				"Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
+		return ctrl.Result{}, err
+	}
+
+	// 创建或更新选择 Deployment Pod 的 ClusterIP Service 和 headless Service
+	if err = r.reconcileServices(ctx, swxfll); err != nil {
+		log.Error(err, "Failed to apply Services", "Service.Namespace", swxfll.Namespace, "Service.Name", swxfll.Name)
+
+		meta.SetStatusCondition(&swxfll.Status.Conditions, metav1.Condition{Type: typeAvailableSwxfll,
+			Status: metav1.ConditionFalse, Reason: "Reconciling",
+			Message: fmt.Sprintf("Failed to apply Services for the custom resource (%s): (%s)", swxfll.Name, err)})
+
+		if err := r.applyStatus(ctx, swxfll); err != nil {
+			log.Error(err, "Failed to update swxfll status")
 			return ctrl.Result{}, err
 		}
 
-		// Deployment created successfully
-		// We will requeue the reconciliation so that we can ensure the state
-		// and move forward for the next operations
-		return ctrl.Result{RequeueAfter: time.Minute}, nil
-	} else if err != nil {
-		log.Error(err, "Failed to get Deployment")
-		// Let's return the error for the reconciliation be re-trigged again
 		return ctrl.Result{}, err
 	}
 
-	// CRD API 定义了 swxfll 类型，具有 swxfll.Size 字段
-	// 用于设置集群中所需状态的 Deployment 实例数量。
-	// 因此，以下代码将确保 Deployment 的大小与正在调和的 Custom Resource 的 Size spec 相同。
-	size := swxfll.Spec.Size
-	if *found.Spec.Replicas != size {
-		found.Spec.Replicas = &size
-		if err = r.Update(ctx, found); err != nil {
-			log.Error(err, "Failed to update Deployment",
-				"Deployment.Namespace", found.Namespace, "Deployment.Name", found.Name)
-
-			// Re-fetch the memcached Custom Resource before update the status
-			// so that we have the latest state of the resource on the cluster and we will avoid
-			// raise the issue "the object has been modified, please apply
-			// your changes to the latest version and try again" which would re-trigger the reconciliation
-			if err := r.Get(ctx, req.NamespacedName, swxfll); err != nil {
-				log.Error(err, "Failed to re-fetch swxfll")
-				return ctrl.Result{}, err
-			}
+	// 多于一个实例时通过 PodDisruptionBudget 限制节点排空等主动驱逐同时驱逐的实例数
+	if err = r.reconcileDisruptionBudget(ctx, swxfll); err != nil {
+		log.Error(err, "Failed to reconcile PodDisruptionBudget",
+			"PodDisruptionBudget.Namespace", swxfll.Namespace, "PodDisruptionBudget.Name", swxfll.Name)
+		r.Recorder.Event(swxfll, "Warning", "PodDisruptionBudgetFailed",
+			fmt.Sprintf("Failed to reconcile PodDisruptionBudget %s/%s: %s", swxfll.Namespace, swxfll.Name, err))
+		return ctrl.Result{}, err
+	}
 
-			// The following implementation will update the status
-			meta.SetStatusCondition(&swxfll.Status.Conditions, metav1.Condition{Type: typeAvailableSwxfll,
-				Status: metav1.ConditionFalse, Reason: "Resizing",
-				Message: fmt.Sprintf("Failed to update the size for the custom resource (%s): (%s)", swxfll.Name, err)})
+	// 设置了 Spec.AllowedClients 时通过 NetworkPolicy 限制可以连接实例的 Pod
+	if err = r.reconcileNetworkPolicy(ctx, swxfll); err != nil {
+		log.Error(err, "Failed to reconcile NetworkPolicy",
+			"NetworkPolicy.Namespace", swxfll.Namespace, "NetworkPolicy.Name", swxfll.Name)
+		r.Recorder.Event(swxfll, "Warning", "NetworkPolicyFailed",
+			fmt.Sprintf("Failed to reconcile NetworkPolicy %s/%s: %s", swxfll.Namespace, swxfll.Name, err))
+		return ctrl.Result{}, err
+	}
 
-			if err := r.Status().Update(ctx, swxfll); err != nil {
-				log.Error(err, "Failed to update Memcached status")
-				return ctrl.Result{}, err
-			}
+	// 开启监控且安装了 Prometheus Operator 时创建 ServiceMonitor，关闭监控时删除它
+	if err = r.reconcileServiceMonitor(ctx, swxfll); err != nil {
+		log.Error(err, "Failed to reconcile ServiceMonitor",
+			"ServiceMonitor.Namespace", swxfll.Namespace, "ServiceMonitor.Name", swxfll.Name)
+		r.Recorder.Event(swxfll, "Warning", "ServiceMonitorFailed",
+			fmt.Sprintf("Failed to reconcile ServiceMonitor %s/%s: %s", swxfll.Namespace, swxfll.Name, err))
+		return ctrl.Result{}, err
+	}
+
+	// 切换 Spec.WorkloadType 后，新的工作负载可用时删除旧的工作负载
+	rollout := rolloutForWorkload(workload)
+	migrating, err := r.migrateWorkload(ctx, swxfll, rollout)
+	if err != nil {
+		log.Error(err, "Failed to migrate workload", "to", kind)
+		return ctrl.Result{}, err
+	}
+
+	// 通过 scale 子资源暴露副本数和 Pod 的 selector，kubectl scale 和 HPA 修改的是 Spec.Size，
+	// 工作负载的副本数始终只由 operator 写入
+	swxfll.Status.Replicas = rollout.current
+	swxfll.Status.Selector = labels.SelectorFromSet(selectorLabelsForSwxfll(swxfll.Name)).String()
+	swxfll.Status.ReadyReplicas = rollout.ready
+
+	// 记录所有就绪 Pod 的地址，客户端可以直接从状态中获取服务器列表
+	pods, err := r.readyPodsForSwxfll(ctx, swxfll)
+	if err != nil {
+		log.Error(err, "Failed to list pods", "Swxfll.Namespace", swxfll.Namespace, "Swxfll.Name", swxfll.Name)
+		return ctrl.Result{}, err
+	}
+	endpoints := podEndpoints(pods, portForSwxfll(swxfll))
+	swxfll.Status.Endpoints = endpoints
 
+	// 将就绪 Pod 的服务器列表发布到 ConfigMap，客户端无需自己列出 Pod 就能构建一致性哈希环
+	if err = r.reconcileServerList(ctx, swxfll, pods); err != nil {
+		log.Error(err, "Failed to apply server list ConfigMap",
+			"ConfigMap.Namespace", swxfll.Namespace, "ConfigMap.Name", serverListConfigMapName(swxfll.Name))
+		return ctrl.Result{}, err
+	}
+
+	// 证书续期后让 memcached 重新加载证书文件，无需重启 Pod；开启认证时 Pod 已经通过滚动更新加载新的证书
+	var tlsConfig *tls.Config
+	certificatesReloaded := false
+	if tlsState != nil {
+		if credentials == nil {
+			certificatesReloaded = r.reloadCertificates(ctx, endpoints, tlsState)
+		}
+		tlsConfig = tlsState.clientConfig()
+	}
+
+	// 开启代理时根据就绪 Pod 渲染 mcrouter 配置并部署 mcrouter，关闭时删除它
+	if err = r.reconcileProxy(ctx, swxfll, endpoints); err != nil {
+		log.Error(err, "Failed to reconcile mcrouter proxy",
+			"Deployment.Namespace", swxfll.Namespace, "Deployment.Name", proxyName(swxfll.Name))
+		r.Recorder.Event(swxfll, "Warning", "ProxyFailed",
+			fmt.Sprintf("Failed to reconcile mcrouter proxy %s/%s: %s", swxfll.Namespace, proxyName(swxfll.Name), err))
+		return ctrl.Result{}, err
+	}
+	// 距离上一次采集不足 StatsInterval 时保留上一次的统计信息
+	if r.StatsInterval > 0 && statsScrapeWait(swxfll.Status.Stats, r.StatsInterval, time.Now()) == 0 {
+		stats := r.collectStats(ctx, endpoints, tlsConfig, credentials)
+		if stats != nil {
+			stats.EvictionsPerSecond = evictionsPerSecond(swxfll.Status.Stats, stats)
+		}
+		swxfll.Status.Stats = stats
+	}
+	recordMetrics(swxfll)
+
+	// 根据统计信息自动调整 Spec.Size，每个决策都记录为事件并写入状态以便审计
+	if swxfll.Spec.Autoscaling == nil {
+		swxfll.Status.Autoscaling = nil
+	} else if decision, ok := autoscale(swxfll, time.Now()); ok {
+		if err := r.scaleSwxfll(ctx, swxfll, decision.ToReplicas); err != nil {
+			log.Error(err, "Failed to scale swxfll", "from", decision.FromReplicas, "to", decision.ToReplicas)
 			return ctrl.Result{}, err
 		}
+		log.Info("自动扩缩容", "from", decision.FromReplicas, "to", decision.ToReplicas, "reason", decision.Reason)
+		eventReason := "ScaledUp"
+		if decision.ToReplicas < decision.FromReplicas {
+			eventReason = "ScaledDown"
+		}
+		r.Recorder.Event(swxfll, "Normal", eventReason,
+			fmt.Sprintf("Scaled from %d to %d replicas: %s", decision.FromReplicas, decision.ToReplicas, decision.Message))
+		recordScalingDecision(swxfll, decision)
+	}
 
-		// Now, that we update the size we want to requeue the reconciliation
-		// so that we can ensure that we have the latest state of the resource before
-		// update. Also, it will help ensure the desired state on the cluster
-		return ctrl.Result{Requeue: true}, nil
+	// 只有在新版本滚动更新到全部副本并且可用之后，才更新 status.currentVersion 和 status.currentImage
+	if rollout.complete() {
+		template := podTemplateOf(workload)
+		if version := template.Labels[versionLabel]; swxfll.Status.CurrentVersion != version {
+			if swxfll.Status.CurrentVersion != "" {
+				r.Recorder.Event(swxfll, "Normal", "Upgraded",
+					fmt.Sprintf("Custom resource %s upgraded from version %s to %s", swxfll.Name, swxfll.Status.CurrentVersion, version))
+			}
+			swxfll.Status.CurrentVersion = version
+		}
+		if container := findContainer(template.Spec.Containers, "swxfll"); container != nil {
+			swxfll.Status.CurrentImage = container.Image
+		}
 	}
 
-	// The following implementation will update the status
-	meta.SetStatusCondition(&swxfll.Status.Conditions, metav1.Condition{Type: typeAvailableSwxfll,
-		Status: metav1.ConditionTrue, Reason: "Reconciling",
-		Message: fmt.Sprintf("Deployment for custom resource (%s) with %d replicas created successfully", swxfll.Name, size)})
+	// Available 和 Progressing 由工作负载自身的状态计算，工作负载状态的每次变化都会触发调和。
+	// 刚创建的工作负载还没有可用的副本，此时 Available 为 False、Progressing 为 True。
+	available, progressing := rolloutConditions(workload)
+	if migrating {
+		progressing = metav1.Condition{Type: typeProgressingSwxfll, Status: metav1.ConditionTrue,
+			Reason:  "Migrating",
+			Message: fmt.Sprintf("Migrating the instances to %s %s: %s", kind, swxfll.Name, progressing.Message)}
+	}
+	if progressing.Reason == progressDeadlineExceededReason {
+		if cond := meta.FindStatusCondition(swxfll.Status.Conditions, typeProgressingSwxfll); cond == nil || cond.Reason != progressDeadlineExceededReason {
+			r.Recorder.Event(swxfll, "Warning", progressDeadlineExceededReason, progressing.Message)
+		}
+	}
+	meta.SetStatusCondition(&swxfll.Status.Conditions, available)
+	meta.SetStatusCondition(&swxfll.Status.Conditions, progressing)
+	if len(drifted) > 0 {
+		meta.SetStatusCondition(&swxfll.Status.Conditions, metav1.Condition{Type: typeDriftedSwxfll,
+			Status: metav1.ConditionTrue, Reason: "DriftCorrected",
+			Message: fmt.Sprintf("Reverted drifted fields of %s %s: %s", kind, workload.GetName(), strings.Join(drifted, ", "))})
+	} else {
+		meta.SetStatusCondition(&swxfll.Status.Conditions, metav1.Condition{Type: typeDriftedSwxfll,
+			Status: metav1.ConditionFalse, Reason: "InSync",
+			Message: fmt.Sprintf("%s for custom resource (%s) matches the desired state", kind, swxfll.Name)})
+	}
 
-	if err := r.Status().Update(ctx, swxfll); err != nil {
+	swxfll.Status.ObservedGeneration = swxfll.Generation
+	swxfll.Status.LastReconcileTime = &metav1.Time{Time: time.Now()}
+	if err := r.applyStatus(ctx, swxfll); err != nil {
 		log.Error(err, "Failed to update swxfll status")
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	// 统计信息在 memcached 内部变化，不会产生任何事件，因此需要定期重新调和来刷新；
+	// 生成的证书需要在续期时间到达时重新调和，重新加载了证书的 Pod 需要稍后确认加载的是新证书
+	requeueAfter := r.StatsInterval
+	if r.StatsInterval > 0 {
+		if wait := statsScrapeWait(swxfll.Status.Stats, r.StatsInterval, time.Now()); wait > 0 {
+			requeueAfter = wait
+		}
+	}
+	if tlsState != nil && !tlsState.renewAt.IsZero() {
+		if untilRenew := time.Until(tlsState.renewAt); requeueAfter == 0 || untilRenew < requeueAfter {
+			requeueAfter = untilRenew
+		}
+	}
+	if certificatesReloaded && (requeueAfter == 0 || certificateReloadRecheck < requeueAfter) {
+		requeueAfter = certificateReloadRecheck
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// apply 以 fieldManager 的身份通过 server-side apply 写入 operator 拥有的对象。
+// obj 必须设置 apiVersion 和 kind，并且只包含 operator 需要拥有的字段。
+func (r *SwxfllReconciler) apply(ctx context.Context, obj client.Object) error {
+	return r.Patch(ctx, obj, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership)
+}
+
+// applyStatus 通过 server-side apply 写入 swxfll 的状态，
+// 成功后用 API Server 返回的最新对象刷新 swxfll，以便后续的更新使用最新的 resourceVersion。
+func (r *SwxfllReconciler) applyStatus(ctx context.Context, swxfll *cachev1beta1.Swxfll) error {
+	patch := &cachev1beta1.Swxfll{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: cachev1beta1.GroupVersion.String(),
+			Kind:       "Swxfll",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      swxfll.Name,
+			Namespace: swxfll.Namespace,
+		},
+		Status: swxfll.Status,
+	}
+	if err := r.Status().Patch(ctx, patch, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership); err != nil {
+		return err
+	}
+	patch.DeepCopyInto(swxfll)
+	return nil
 }
 
 // finalizeSwxfll 将在删除 CR 之前执行所需的操作。
-func (r *SwxfllReconciler) doFinalizerOperationsForSwxfll(cr *cachev1alpha1.Swxfll) {
+func (r *SwxfllReconciler) doFinalizerOperationsForSwxfll(cr *cachev1beta1.Swxfll) {
 	// TODO（用户）：在 CR 被删除之前，添加操作清理步骤。
 	// 操作清理步骤的示例包括执行备份和删除不由此 CR 拥有的资源，比如 PVC。
 
@@ -307,61 +557,56 @@ func (r *SwxfllReconciler) doFinalizerOperationsForSwxfll(cr *cachev1alpha1.Swxf
 	r.Recorder.Event(cr, "Warning", "Deleting",
 		fmt.Sprintf("自定义资源 %s 正在从命名空间 %s 中删除", cr.Name, cr.Namespace))
 
+	// 删除该自定义资源的指标，避免 metrics 端点继续暴露已删除实例的值
+	deleteMetrics(cr)
+
 }
 
 // deploymentForSwxfll 返回一个 Swxfll Deployment 对象
-func (r *SwxfllReconciler) deploymentForSwxfll(swxfll *cachev1alpha1.Swxfll) (
+func (r *SwxfllReconciler) deploymentForSwxfll(swxfll *cachev1beta1.Swxfll) (
 	*appsv1.Deployment, error) {
-	ls := labelsForSwxfll(swxfll.Name)
+	selector := selectorLabelsForSwxfll(swxfll.Name)
 	replicas := swxfll.Spec.Size
 
-	// 获取 Operand 镜像
-	image, err := imageForSwxfll()
+	// 获取 Operand 镜像和版本
+	image, version, err := operandForSwxfll(swxfll)
+	if err != nil {
+		return nil, err
+	}
+	ls := labelsForSwxfll(swxfll.Name, version)
+
+	// 根据 Spec.Memcached 渲染 memcached 参数
+	args, err := memcachedArgs(swxfll)
 	if err != nil {
 		return nil, err
 	}
 
 	dep := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "Deployment",
+		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      swxfll.Name,
 			Namespace: swxfll.Namespace,
+			Labels:    ls,
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: &replicas,
 			Selector: &metav1.LabelSelector{
-				MatchLabels: ls,
+				MatchLabels: selector,
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: ls,
 				},
 				Spec: corev1.PodSpec{
-					// TODO（用户）：取消下面代码的注释以根据您的解决方案支持的平台配置 nodeAffinity 表达式。
-					// 支持多种架构被认为是最佳实践。使用 makefile 目标 docker-buildx 构建您的 manager 镜像。
-					// 您还可以使用 docker manifest inspect <image> 来检查支持的平台。
-					// 更多信息请参阅：https://kubernetes.io/docs/concepts/scheduling-eviction/assign-pod-node/#node-affinity
-					//Affinity: &corev1.Affinity{
-					//	NodeAffinity: &corev1.NodeAffinity{
-					//		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
-					//			NodeSelectorTerms: []corev1.NodeSelectorTerm{
-					//				{
-					//					MatchExpressions: []corev1.NodeSelectorRequirement{
-					//						{
-					//							Key:      "kubernetes.io/arch",
-					//							Operator: "In",
-					//							Values:   []string{"amd64", "arm64", "ppc64le", "s390x"},
-					//						},
-					//						{
-					//							Key:      "kubernetes.io/os",
-					//							Operator: "In",
-					//							Values:   []string{"linux"},
-					//						},
-					//					},
-					//				},
-					//			},
-					//		},
-					//	},
-					//},
+					// 根据 Spec.Scheduling 配置 Pod 的调度，默认尽量把实例分散到不同的节点和可用区
+					NodeSelector:              nodeSelectorForSwxfll(swxfll),
+					Tolerations:               tolerationsForSwxfll(swxfll),
+					Affinity:                  affinityForSwxfll(swxfll),
+					PriorityClassName:         schedulingSpecForSwxfll(swxfll).PriorityClassName,
+					TopologySpreadConstraints: topologySpreadConstraintsForSwxfll(swxfll),
 					SecurityContext: &corev1.PodSecurityContext{
 						RunAsNonRoot: &[]bool{true}[0],
 						// 重要提示：seccomProfile 是在 Kubernetes 1.19 中引入的
@@ -391,16 +636,57 @@ func (r *SwxfllReconciler) deploymentForSwxfll(swxfll *cachev1alpha1.Swxfll) (
 								},
 							},
 							Ports: []corev1.ContainerPort{{
-								ContainerPort: swxfll.Spec.ContainerPort,
+								ContainerPort: portForSwxfll(swxfll),
 								Name:          "swxfll",
+								Protocol:      corev1.ProtocolTCP,
 							}},
-							Command: []string{"swxfll", "-m=64", "-o", "modern", "-v"},
+							Command:   []string{memcachedCommand},
+							Args:      args,
+							Resources: resourcesForSwxfll(swxfll),
 						}},
 				},
 			},
 		},
 	}
 
+	// 开启 TLS 时挂载证书 Secret
+	if tlsEnabled(swxfll) {
+		spec := &dep.Spec.Template.Spec
+		spec.Volumes = append(spec.Volumes, tlsVolumeForSwxfll(swxfll))
+		spec.Containers[0].VolumeMounts = append(spec.Containers[0].VolumeMounts,
+			corev1.VolumeMount{Name: tlsVolumeName, MountPath: tlsMountPath, ReadOnly: true})
+	}
+
+	// 开启认证时挂载 SASL 配置和密码文件，凭据变化时滚动更新。
+	// SASL 会关闭文本协议，operator 无法通过 refresh_certs 让 memcached 重新加载证书，证书续期时同样滚动更新。
+	if authEnabled(swxfll) {
+		spec := &dep.Spec.Template.Spec
+		spec.Volumes = append(spec.Volumes, authVolumeForSwxfll(swxfll))
+		spec.Containers[0].VolumeMounts = append(spec.Containers[0].VolumeMounts,
+			corev1.VolumeMount{Name: saslVolumeName, MountPath: saslMountPath, ReadOnly: true})
+		spec.Containers[0].Env = append(spec.Containers[0].Env, authEnvForSwxfll()...)
+
+		annotations := map[string]string{}
+		if auth := swxfll.Status.Auth; auth != nil && auth.LastRotationTime != nil {
+			annotations[credentialsRotatedAnnotation] = auth.LastRotationTime.UTC().Format(time.RFC3339)
+		}
+		if tlsEnabled(swxfll) && swxfll.Status.TLS != nil {
+			annotations[tlsSerialAnnotation] = swxfll.Status.TLS.SerialNumber
+		}
+		if len(annotations) > 0 {
+			dep.Spec.Template.Annotations = annotations
+		}
+	}
+
+	// 开启监控时注入 exporter sidecar；关闭监控后 apply 的对象中不再包含它，server-side apply 会将其移除
+	if monitoringEnabled(swxfll) {
+		exporter, err := exporterContainerForSwxfll(swxfll)
+		if err != nil {
+			return nil, err
+		}
+		dep.Spec.Template.Spec.Containers = append(dep.Spec.Template.Spec.Containers, exporter)
+	}
+
 	// 为 Deployment 设置 ownerRef
 	// 更多信息请参阅：https://kubernetes.io/docs/concepts/overview/working-with-objects/owners-dependents/
 	if err := ctrl.SetControllerReference(swxfll, dep, r.Scheme); err != nil {
@@ -412,16 +698,18 @@ func (r *SwxfllReconciler) deploymentForSwxfll(swxfll *cachev1alpha1.Swxfll) (
 
 // labelsForSwxfll 返回用于选择资源的标签
 // 更多信息请参阅：https://kubernetes.io/docs/concepts/overview/working-with-objects/common-labels/
-func labelsForSwxfll(name string) map[string]string {
-	var imageTag string
-	image, err := imageForSwxfll()
-	if err == nil {
-		imageTag = strings.Split(image, ":")[1]
-	}
+func labelsForSwxfll(name, version string) map[string]string {
+	ls := selectorLabelsForSwxfll(name)
+	ls[versionLabel] = version
+	return ls
+}
+
+// selectorLabelsForSwxfll 返回 Deployment selector 使用的标签。
+// selector 不可变，因此不能包含 app.kubernetes.io/version 这类会随镜像变化的标签。
+func selectorLabelsForSwxfll(name string) map[string]string {
 	return map[string]string{
 		"app.kubernetes.io/name":       "Swxfll",
 		"app.kubernetes.io/instance":   name,
-		"app.kubernetes.io/version":    imageTag,
 		"app.kubernetes.io/part-of":    "swxfll-operator",
 		"app.kubernetes.io/created-by": "controller-manager",
 	}
@@ -441,9 +729,21 @@ func imageForSwxfll() (string, error) {
 // SetupWithManager sets up the controller with the Manager.
 func (r *SwxfllReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// NewControllerManagedBy() 提供了一个控制器生成器，允许各种控制器配置。
+	// 每次调和都会更新 status.lastReconcileTime，因此忽略 Swxfll 只有状态变化的更新事件，避免无限调和；
+	// 删除时 API Server 会增加 generation，所以 finalizer 逻辑不受影响。
+	// 证书和凭据的 Secret 可能由用户或 cert-manager 管理，不一定由 Swxfll 拥有，因此根据 Spec.TLS 和 Spec.Auth 映射它的事件。
+	// Secret 只缓存元数据，避免 manager 在内存中保存集群中所有 Secret 的内容；读取 Secret 时直接访问 API Server（见 cmd/main.go）。
+	// ServiceMonitor 的 CRD 不一定存在，因此不 watch 它，由定期的重新调和纠正对它的修改。
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&cachev1alpha1.Swxfll{}).
+		For(&cachev1beta1.Swxfll{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.ConfigMap{}).
+		Owns(&networkingv1.NetworkPolicy{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(swxfllForPod)).
+		WatchesMetadata(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.swxfllsForSecret)).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 2}).
 		Complete(r)
 }
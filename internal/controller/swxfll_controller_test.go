@@ -18,16 +18,30 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	cachev1alpha1 "github.com/swxfll/operator-sdk-demo/api/v1alpha1"
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+	"github.com/swxfll/operator-sdk-demo/internal/memcached/memcachedtest"
 )
 
 var _ = Describe("Swxfll Controller", func() {
@@ -40,13 +54,13 @@ var _ = Describe("Swxfll Controller", func() {
 			Name:      resourceName,
 			Namespace: "default", // TODO(user):Modify as needed
 		}
-		swxfll := &cachev1alpha1.Swxfll{}
+		swxfll := &cachev1beta1.Swxfll{}
 
 		BeforeEach(func() {
 			By("creating the custom resource for the Kind Swxfll")
 			err := k8sClient.Get(ctx, typeNamespacedName, swxfll)
 			if err != nil && errors.IsNotFound(err) {
-				resource := &cachev1alpha1.Swxfll{
+				resource := &cachev1beta1.Swxfll{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      resourceName,
 						Namespace: "default",
@@ -59,7 +73,7 @@ var _ = Describe("Swxfll Controller", func() {
 
 		AfterEach(func() {
 			// TODO(user): Cleanup logic after each test, like removing the resource instance.
-			resource := &cachev1alpha1.Swxfll{}
+			resource := &cachev1beta1.Swxfll{}
 			err := k8sClient.Get(ctx, typeNamespacedName, resource)
 			Expect(err).NotTo(HaveOccurred())
 
@@ -81,4 +95,1260 @@ var _ = Describe("Swxfll Controller", func() {
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
 		})
 	})
+
+	Context("When the owned Deployment drifts from the desired state", func() {
+		const resourceName = "test-drift"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			By("setting the operand image")
+			Expect(os.Setenv("SWXFLL_IMAGE", "example.com/image:test")).To(Succeed())
+
+			By("creating the custom resource for the Kind Swxfll")
+			resource := &cachev1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: cachev1beta1.SwxfllSpec{
+					Size:       1,
+					Networking: &cachev1beta1.NetworkingSpec{Port: 11211},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_IMAGE")).To(Succeed())
+		})
+
+		It("should revert the drifted fields and report them", func() {
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &SwxfllReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+
+			By("reconciling until the Deployment exists")
+			for i := 0; i < 2; i++ {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			By("editing the Deployment by hand")
+			found := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, found)).To(Succeed())
+			found.Spec.Template.Spec.Containers[0].Image = "example.com/image:drifted"
+			found.Spec.Template.Spec.Containers[0].Command = []string{"sh"}
+			Expect(k8sClient.Update(ctx, found)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("checking the Deployment was converged back")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, found)).To(Succeed())
+			Expect(found.Spec.Template.Spec.Containers[0].Image).To(Equal("example.com/image:test"))
+			Expect(found.Spec.Template.Spec.Containers[0].Command).To(Equal([]string{memcachedCommand}))
+
+			By("checking the drift was reported")
+			Expect(recorder.Events).To(Receive(ContainSubstring("DriftDetected")))
+			swxfll := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			cond := meta.FindStatusCondition(swxfll.Status.Conditions, typeDriftedSwxfll)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+			Expect(cond.Message).To(ContainSubstring(".image"))
+			Expect(cond.Message).To(ContainSubstring(".command"))
+		})
+
+		It("should keep fields owned by other field managers", func() {
+			controllerReconciler := &SwxfllReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			By("reconciling until the Deployment exists")
+			for i := 0; i < 2; i++ {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			By("adding a pod template annotation as another controller would")
+			found := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, found)).To(Succeed())
+			if found.Spec.Template.Annotations == nil {
+				found.Spec.Template.Annotations = map[string]string{}
+			}
+			found.Spec.Template.Annotations["sidecar.example.com/inject"] = "true"
+			Expect(k8sClient.Update(ctx, found, client.FieldOwner("sidecar-injector"))).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("checking the annotation survived and the operator applied its fields")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, found)).To(Succeed())
+			Expect(found.Spec.Template.Annotations).To(HaveKeyWithValue("sidecar.example.com/inject", "true"))
+			managers := []string{}
+			for _, entry := range found.ManagedFields {
+				if entry.Operation == metav1.ManagedFieldsOperationApply {
+					managers = append(managers, entry.Manager)
+				}
+			}
+			Expect(managers).To(ContainElement(fieldManager))
+		})
+	})
+
+	Context("When exposing the instances through Services", func() {
+		const resourceName = "test-service"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			Expect(os.Setenv("SWXFLL_IMAGE", "example.com/image:test")).To(Succeed())
+
+			By("creating the custom resource with a NodePort Service")
+			resource := &cachev1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: cachev1beta1.SwxfllSpec{
+					Size: 1,
+					Networking: &cachev1beta1.NetworkingSpec{
+						Port: 11211,
+						Service: &cachev1beta1.ServiceSpec{
+							Type:        corev1.ServiceTypeNodePort,
+							Annotations: map[string]string{"example.com/team": "cache"},
+							Port:        11311,
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_IMAGE")).To(Succeed())
+		})
+
+		It("should create the client-facing and headless Services", func() {
+			controllerReconciler := &SwxfllReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			svc := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, svc)).To(Succeed())
+			Expect(svc.Spec.Type).To(Equal(corev1.ServiceTypeNodePort))
+			Expect(svc.Annotations).To(HaveKeyWithValue("example.com/team", "cache"))
+			Expect(svc.Spec.Ports).To(HaveLen(1))
+			Expect(svc.Spec.Ports[0].Port).To(Equal(int32(11311)))
+			Expect(svc.Spec.Selector).To(Equal(selectorLabelsForSwxfll(resourceName)))
+
+			headless := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      headlessServiceName(resourceName),
+				Namespace: "default",
+			}, headless)).To(Succeed())
+			Expect(headless.Spec.ClusterIP).To(Equal(corev1.ClusterIPNone))
+			Expect(headless.Spec.Selector).To(Equal(selectorLabelsForSwxfll(resourceName)))
+		})
+	})
+
+	Context("When monitoring is enabled", func() {
+		const resourceName = "test-monitoring"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			Expect(os.Setenv("SWXFLL_IMAGE", "example.com/image:test")).To(Succeed())
+			Expect(os.Setenv("SWXFLL_EXPORTER_IMAGE", "example.com/exporter:test")).To(Succeed())
+
+			resource := &cachev1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: cachev1beta1.SwxfllSpec{
+					Size:       1,
+					Monitoring: &cachev1beta1.MonitoringSpec{Enabled: true},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_IMAGE")).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_EXPORTER_IMAGE")).To(Succeed())
+		})
+
+		It("should add the exporter and metrics port and remove them when disabled", func() {
+			controllerReconciler := &SwxfllReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+			headlessName := types.NamespacedName{Name: headlessServiceName(resourceName), Namespace: "default"}
+
+			By("reconciling without the ServiceMonitor CRD installed")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			found := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, found)).To(Succeed())
+			exporter := findContainer(found.Spec.Template.Spec.Containers, exporterContainerName)
+			Expect(exporter).NotTo(BeNil())
+			Expect(exporter.Image).To(Equal("example.com/exporter:test"))
+
+			headless := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, headlessName, headless)).To(Succeed())
+			Expect(headless.Spec.Ports).To(ContainElement(HaveField("Name", metricsPortName)))
+
+			By("disabling monitoring")
+			swxfll := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			swxfll.Spec.Monitoring.Enabled = false
+			Expect(k8sClient.Update(ctx, swxfll)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, found)).To(Succeed())
+			Expect(findContainer(found.Spec.Template.Spec.Containers, exporterContainerName)).To(BeNil())
+			Expect(k8sClient.Get(ctx, headlessName, headless)).To(Succeed())
+			Expect(headless.Spec.Ports).NotTo(ContainElement(HaveField("Name", metricsPortName)))
+		})
+	})
+
+	Context("When the mcrouter proxy is enabled", func() {
+		const resourceName = "test-proxy"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			Expect(os.Setenv("SWXFLL_IMAGE", "example.com/image:test")).To(Succeed())
+			Expect(os.Setenv("SWXFLL_MCROUTER_IMAGE", "example.com/mcrouter:test")).To(Succeed())
+
+			resource := &cachev1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: cachev1beta1.SwxfllSpec{
+					Size:  1,
+					Proxy: &cachev1beta1.ProxySpec{Enabled: true},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_IMAGE")).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_MCROUTER_IMAGE")).To(Succeed())
+		})
+
+		It("should deploy mcrouter and remove it when disabled", func() {
+			controllerReconciler := &SwxfllReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+			proxyKey := types.NamespacedName{Name: proxyName(resourceName), Namespace: "default"}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("checking the mcrouter Deployment, Service and config")
+			dep := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, proxyKey, dep)).To(Succeed())
+			Expect(*dep.Spec.Replicas).To(Equal(int32(2)))
+			Expect(dep.Spec.Template.Spec.Containers[0].Image).To(Equal("example.com/mcrouter:test"))
+			Expect(dep.Spec.Template.Annotations).To(HaveKey(proxyConfigHashAnnotation))
+
+			svc := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, proxyKey, svc)).To(Succeed())
+			Expect(svc.Spec.Selector).To(Equal(proxySelectorLabelsForSwxfll(resourceName)))
+			Expect(svc.Spec.Ports[0].Port).To(Equal(cachev1beta1.DefaultProxyPort))
+
+			cm := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, proxyKey, cm)).To(Succeed())
+			Expect(cm.Data).To(HaveKey(proxyConfigKey))
+
+			swxfll := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			Expect(swxfll.Status.Proxy).NotTo(BeNil())
+			Expect(swxfll.Status.Proxy.Endpoint).To(Equal("test-proxy-mcrouter.default.svc:5000"))
+			Expect(swxfll.Status.Proxy.ConfigHash).To(Equal(dep.Spec.Template.Annotations[proxyConfigHashAnnotation]))
+
+			By("disabling the proxy")
+			swxfll.Spec.Proxy.Enabled = false
+			Expect(k8sClient.Update(ctx, swxfll)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, proxyKey, &appsv1.Deployment{}))).To(BeTrue())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, proxyKey, &corev1.Service{}))).To(BeTrue())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, proxyKey, &corev1.ConfigMap{}))).To(BeTrue())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			Expect(swxfll.Status.Proxy).To(BeNil())
+		})
+	})
+
+	Context("When TLS is enabled", func() {
+		const resourceName = "test-tls"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			Expect(os.Setenv("SWXFLL_IMAGE", "example.com/image:test")).To(Succeed())
+
+			resource := &cachev1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: cachev1beta1.SwxfllSpec{
+					Size: 1,
+					TLS:  &cachev1beta1.TLSSpec{Enabled: true},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_IMAGE")).To(Succeed())
+		})
+
+		It("should generate the certificates and renew them before they expire", func() {
+			controllerReconciler := &SwxfllReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			By("requeueing when the certificate is due for renewal")
+			Expect(result.RequeueAfter).To(BeNumerically("~", 60*24*time.Hour, time.Minute))
+
+			By("checking the generated Secrets and the status")
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "test-tls-tls", Namespace: "default"}, secret)).To(Succeed())
+			Expect(secret.Type).To(Equal(corev1.SecretTypeTLS))
+			Expect(secret.Data).To(HaveKey(tlsCAKey))
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "test-tls-tls-ca", Namespace: "default"}, &corev1.Secret{})).To(Succeed())
+
+			swxfll := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			Expect(swxfll.Status.TLS).NotTo(BeNil())
+			Expect(swxfll.Status.TLS.SecretName).To(Equal("test-tls-tls"))
+			Expect(swxfll.Status.TLS.ServerName).To(Equal("test-tls.default.svc"))
+			Expect(swxfll.Status.TLS.CANotAfter).NotTo(BeNil())
+			serial := swxfll.Status.TLS.SerialNumber
+
+			found := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, found)).To(Succeed())
+			Expect(found.Spec.Template.Spec.Containers[0].Args).To(ContainElement("-Z"))
+
+			By("keeping the certificate while it is valid")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			Expect(swxfll.Status.TLS.SerialNumber).To(Equal(serial))
+
+			By("renewing the certificate within renewBefore of its expiry")
+			_, err = controllerReconciler.reconcileTLS(ctx, swxfll, time.Now().Add(61*24*time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(swxfll.Status.TLS.SerialNumber).NotTo(Equal(serial))
+		})
+	})
+
+	Context("When SASL authentication is enabled", func() {
+		const resourceName = "test-auth"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			Expect(os.Setenv("SWXFLL_IMAGE", "example.com/image:test")).To(Succeed())
+
+			resource := &cachev1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: cachev1beta1.SwxfllSpec{
+					Size: 1,
+					Auth: &cachev1beta1.AuthSpec{Enabled: true},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_IMAGE")).To(Succeed())
+		})
+
+		It("should generate credentials and keep the previous ones valid after a rotation", func() {
+			controllerReconciler := &SwxfllReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+			authKey := types.NamespacedName{Name: "test-auth-auth", Namespace: "default"}
+			saslKey := types.NamespacedName{Name: "test-auth-sasl", Namespace: "default"}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("checking the generated credentials and password file")
+			credentials := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, authKey, credentials)).To(Succeed())
+			Expect(credentials.Type).To(Equal(corev1.SecretTypeBasicAuth))
+			username := string(credentials.Data[corev1.BasicAuthUsernameKey])
+			current := username + ":" + string(credentials.Data[corev1.BasicAuthPasswordKey])
+
+			sasl := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, saslKey, sasl)).To(Succeed())
+			Expect(passwordDBEntries(sasl)).To(Equal([]string{current}))
+
+			found := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, found)).To(Succeed())
+			Expect(found.Spec.Template.Spec.Containers[0].Args).To(ContainElement("-S"))
+			rotated := found.Spec.Template.Annotations[credentialsRotatedAnnotation]
+			Expect(rotated).NotTo(BeEmpty())
+
+			By("rotating the generated credentials by deleting their Secret")
+			Expect(k8sClient.Delete(ctx, credentials)).To(Succeed())
+			time.Sleep(time.Second)
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, authKey, credentials)).To(Succeed())
+			Expect(string(credentials.Data[corev1.BasicAuthUsernameKey])).NotTo(Equal(username))
+			Expect(k8sClient.Get(ctx, saslKey, sasl)).To(Succeed())
+			entries := passwordDBEntries(sasl)
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[1]).To(Equal(current))
+
+			swxfll := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			Expect(swxfll.Status.Auth).NotTo(BeNil())
+			Expect(swxfll.Status.Auth.PreviousUsername).To(Equal(username))
+
+			By("rolling the pods to load the new password file")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, found)).To(Succeed())
+			Expect(found.Spec.Template.Annotations[credentialsRotatedAnnotation]).NotTo(Equal(rotated))
+		})
+	})
+
+	Context("When clients are restricted by a NetworkPolicy", func() {
+		const resourceName = "test-networkpolicy"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			Expect(os.Setenv("SWXFLL_IMAGE", "example.com/image:test")).To(Succeed())
+
+			resource := &cachev1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: cachev1beta1.SwxfllSpec{
+					Size: 1,
+					AllowedClients: []cachev1beta1.NetworkPeer{{
+						PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_IMAGE")).To(Succeed())
+		})
+
+		It("should own a NetworkPolicy and remove it when the clients are cleared", func() {
+			controllerReconciler := &SwxfllReconciler{
+				Client:            k8sClient,
+				Scheme:            k8sClient.Scheme(),
+				Recorder:          record.NewFakeRecorder(10),
+				OperatorNamespace: "swxfll-system",
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("checking the NetworkPolicy")
+			np := &networkingv1.NetworkPolicy{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, np)).To(Succeed())
+			Expect(np.Spec.PodSelector.MatchLabels).To(Equal(selectorLabelsForSwxfll(resourceName)))
+			Expect(np.Spec.Ingress).To(HaveLen(1))
+			Expect(np.Spec.Ingress[0].From).To(HaveLen(2))
+			Expect(np.Spec.Ingress[0].Ports[0].Port.IntValue()).To(Equal(int(cachev1beta1.DefaultContainerPort)))
+
+			By("clearing the allowed clients")
+			swxfll := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			swxfll.Spec.AllowedClients = nil
+			Expect(k8sClient.Update(ctx, swxfll)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, typeNamespacedName, &networkingv1.NetworkPolicy{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Context("When limiting voluntary disruptions", func() {
+		const resourceName = "test-disruption-budget"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			Expect(os.Setenv("SWXFLL_IMAGE", "example.com/image:test")).To(Succeed())
+
+			resource := &cachev1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: cachev1beta1.SwxfllSpec{Size: 3},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_IMAGE")).To(Succeed())
+		})
+
+		It("should own a PodDisruptionBudget and remove it when scaled to a single instance", func() {
+			controllerReconciler := &SwxfllReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("checking the default budget")
+			pdb := &policyv1.PodDisruptionBudget{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, pdb)).To(Succeed())
+			Expect(pdb.Spec.Selector.MatchLabels).To(Equal(selectorLabelsForSwxfll(resourceName)))
+			Expect(pdb.Spec.MaxUnavailable.IntValue()).To(Equal(1))
+
+			By("configuring minAvailable")
+			swxfll := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			swxfll.Spec.DisruptionBudget = &cachev1beta1.DisruptionBudgetSpec{MinAvailable: ptrTo(intstr.FromString("50%"))}
+			Expect(k8sClient.Update(ctx, swxfll)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, pdb)).To(Succeed())
+			Expect(pdb.Spec.MaxUnavailable).To(BeNil())
+			Expect(*pdb.Spec.MinAvailable).To(Equal(intstr.FromString("50%")))
+
+			By("scaling to a single instance")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			swxfll.Spec.Size = 1
+			Expect(k8sClient.Update(ctx, swxfll)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, typeNamespacedName, &policyv1.PodDisruptionBudget{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Context("When scheduling is configured", func() {
+		const resourceName = "test-scheduling"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			Expect(os.Setenv("SWXFLL_IMAGE", "example.com/image:test")).To(Succeed())
+
+			resource := &cachev1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: cachev1beta1.SwxfllSpec{
+					Size: 3,
+					Scheduling: &cachev1beta1.SchedulingSpec{
+						NodeSelector: map[string]string{"node-role.example.com/cache": ""},
+						Tolerations: []corev1.Toleration{{
+							Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "cache", Effect: corev1.TaintEffectNoSchedule,
+						}},
+						PriorityClassName: "cache-critical",
+						TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{
+							MaxSkew: 1, TopologyKey: corev1.LabelTopologyZone, WhenUnsatisfiable: corev1.DoNotSchedule,
+						}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_IMAGE")).To(Succeed())
+		})
+
+		It("should place the pods as configured and spread them by default", func() {
+			controllerReconciler := &SwxfllReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			found := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, found)).To(Succeed())
+			spec := found.Spec.Template.Spec
+			Expect(spec.NodeSelector).To(HaveKey("node-role.example.com/cache"))
+			Expect(spec.Tolerations).To(HaveLen(1))
+			Expect(spec.PriorityClassName).To(Equal("cache-critical"))
+			Expect(spec.TopologySpreadConstraints).To(HaveLen(1))
+			Expect(spec.TopologySpreadConstraints[0].LabelSelector.MatchLabels).To(Equal(selectorLabelsForSwxfll(resourceName)))
+			Expect(spec.Affinity.PodAntiAffinity).To(Equal(defaultPodAntiAffinity(resourceName)))
+
+			By("reconciling again without reporting drift")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			swxfll := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(swxfll.Status.Conditions, typeDriftedSwxfll)).To(BeFalse())
+		})
+	})
+
+	Context("When autoscaling is configured", func() {
+		const resourceName = "test-autoscaling"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			Expect(os.Setenv("SWXFLL_IMAGE", "example.com/image:test")).To(Succeed())
+
+			target := int32(80)
+			resource := &cachev1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: cachev1beta1.SwxfllSpec{
+					Size: 1,
+					Autoscaling: &cachev1beta1.AutoscalingSpec{
+						MinReplicas:                    2,
+						MaxReplicas:                    4,
+						TargetMemoryUtilizationPercent: &target,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_IMAGE")).To(Succeed())
+		})
+
+		It("should scale into the bounds and record the decision", func() {
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &SwxfllReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			swxfll := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			Expect(swxfll.Spec.Size).To(Equal(int32(2)))
+			Expect(swxfll.Status.Autoscaling).NotTo(BeNil())
+			Expect(swxfll.Status.Autoscaling.Decisions).To(HaveLen(1))
+			Expect(swxfll.Status.Autoscaling.Decisions[0].Reason).To(Equal("BelowMinReplicas"))
+			Expect(recorder.Events).To(Receive(ContainSubstring("ScaledUp")))
+
+			By("applying the new size to the Deployment on the next reconcile")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			found := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, found)).To(Succeed())
+			Expect(*found.Spec.Replicas).To(Equal(int32(2)))
+		})
+	})
+
+	Context("When switching the workload type", func() {
+		const resourceName = "test-workload"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			Expect(os.Setenv("SWXFLL_IMAGE", "example.com/image:test")).To(Succeed())
+
+			resource := &cachev1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: cachev1beta1.SwxfllSpec{Size: 2},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_IMAGE")).To(Succeed())
+		})
+
+		It("should keep the Deployment until the StatefulSet is available", func() {
+			recorder := record.NewFakeRecorder(20)
+			controllerReconciler := &SwxfllReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &appsv1.Deployment{})).To(Succeed())
+
+			By("switching to a StatefulSet")
+			swxfll := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			swxfll.Spec.WorkloadType = cachev1beta1.WorkloadTypeStatefulSet
+			Expect(k8sClient.Update(ctx, swxfll)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			sts := &appsv1.StatefulSet{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sts)).To(Succeed())
+			Expect(sts.Spec.ServiceName).To(Equal(headlessServiceName(resourceName)))
+			Expect(*sts.Spec.Replicas).To(Equal(int32(2)))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &appsv1.Deployment{})).To(Succeed())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			progressing := meta.FindStatusCondition(swxfll.Status.Conditions, typeProgressingSwxfll)
+			Expect(progressing).NotTo(BeNil())
+			Expect(progressing.Reason).To(Equal("Migrating"))
+			Expect(meta.FindStatusCondition(swxfll.Status.Conditions, typeAvailableSwxfll)).NotTo(BeNil())
+			Expect(meta.FindStatusCondition(swxfll.Status.Conditions, typeDriftedSwxfll)).NotTo(BeNil())
+
+			By("completing the StatefulSet rollout")
+			sts.Status.ObservedGeneration = sts.Generation
+			sts.Status.Replicas = 2
+			sts.Status.UpdatedReplicas = 2
+			sts.Status.ReadyReplicas = 2
+			sts.Status.AvailableReplicas = 2
+			Expect(k8sClient.Status().Update(ctx, sts)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			err = k8sClient.Get(ctx, typeNamespacedName, &appsv1.Deployment{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(swxfll.Status.Conditions, typeAvailableSwxfll)).To(BeTrue())
+			Expect(swxfll.Status.ReadyReplicas).To(Equal(int32(2)))
+		})
+	})
+
+	Context("When upgrading the operand version", func() {
+		const resourceName = "test-version"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		// completeRollout 模拟 Deployment 控制器完成滚动更新
+		completeRollout := func() {
+			found := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, found)).To(Succeed())
+			found.Status.ObservedGeneration = found.Generation
+			found.Status.Replicas = *found.Spec.Replicas
+			found.Status.UpdatedReplicas = *found.Spec.Replicas
+			found.Status.ReadyReplicas = *found.Spec.Replicas
+			found.Status.AvailableReplicas = *found.Spec.Replicas
+			Expect(k8sClient.Status().Update(ctx, found)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			Expect(os.Setenv("SWXFLL_IMAGE", "example.com/image:1.0")).To(Succeed())
+			Expect(os.Setenv("SWXFLL_SUPPORTED_VERSIONS", "1.0,1.1")).To(Succeed())
+
+			resource := &cachev1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: cachev1beta1.SwxfllSpec{
+					Size:       1,
+					Networking: &cachev1beta1.NetworkingSpec{Port: 11211},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_IMAGE")).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_SUPPORTED_VERSIONS")).To(Succeed())
+		})
+
+		It("should only report the new version once the rollout completes", func() {
+			controllerReconciler := &SwxfllReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+			reconcileOnce := func() {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			swxfll := &cachev1beta1.Swxfll{}
+
+			By("rolling out the default version")
+			reconcileOnce()
+			completeRollout()
+			reconcileOnce()
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			Expect(swxfll.Status.CurrentVersion).To(Equal("1.0"))
+
+			By("requesting a supported version")
+			swxfll.Spec.Version = "1.1"
+			Expect(k8sClient.Update(ctx, swxfll)).To(Succeed())
+			reconcileOnce()
+
+			found := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, found)).To(Succeed())
+			Expect(found.Spec.Template.Spec.Containers[0].Image).To(Equal("example.com/image:1.1"))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			Expect(swxfll.Status.CurrentVersion).To(Equal("1.0"))
+			Expect(meta.IsStatusConditionTrue(swxfll.Status.Conditions, typeProgressingSwxfll)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(swxfll.Status.Conditions, typeAvailableSwxfll)).To(BeFalse())
+
+			By("completing the rollout")
+			completeRollout()
+			reconcileOnce()
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			Expect(swxfll.Status.CurrentVersion).To(Equal("1.1"))
+			Expect(meta.IsStatusConditionTrue(swxfll.Status.Conditions, typeProgressingSwxfll)).To(BeFalse())
+			Expect(meta.IsStatusConditionTrue(swxfll.Status.Conditions, typeAvailableSwxfll)).To(BeTrue())
+		})
+
+		It("should refuse versions outside of the allowlist", func() {
+			controllerReconciler := &SwxfllReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			swxfll := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			swxfll.Spec.Version = "2.0"
+			Expect(k8sClient.Update(ctx, swxfll)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).To(MatchError(ContainSubstring("not supported")))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			cond := meta.FindStatusCondition(swxfll.Status.Conditions, typeAvailableSwxfll)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		})
+	})
+
+	Context("When scaling through the scale subresource", func() {
+		const resourceName = "test-scale"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			Expect(os.Setenv("SWXFLL_IMAGE", "example.com/image:test")).To(Succeed())
+
+			resource := &cachev1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: cachev1beta1.SwxfllSpec{
+					Size:       1,
+					Networking: &cachev1beta1.NetworkingSpec{Port: 11211},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_IMAGE")).To(Succeed())
+		})
+
+		It("should scale the Deployment through Spec.Size only", func() {
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &SwxfllReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+			reconcileOnce := func() {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			reconcileOnce()
+			reconcileOnce()
+
+			By("checking the selector is published for the scale subresource")
+			swxfll := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			scale := &autoscalingv1.Scale{}
+			Expect(k8sClient.SubResource("scale").Get(ctx, swxfll, scale)).To(Succeed())
+			Expect(scale.Spec.Replicas).To(Equal(int32(1)))
+			Expect(scale.Status.Selector).To(Equal(swxfll.Status.Selector))
+			selector, err := labels.Parse(scale.Status.Selector)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(selector.Matches(labels.Set(selectorLabelsForSwxfll(resourceName)))).To(BeTrue())
+
+			By("scaling the custom resource like kubectl scale or an HPA would")
+			scale.Spec.Replicas = 3
+			Expect(k8sClient.SubResource("scale").Update(ctx, swxfll, client.WithSubResourceBody(scale))).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			Expect(swxfll.Spec.Size).To(Equal(int32(3)))
+			reconcileOnce()
+
+			found := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, found)).To(Succeed())
+			Expect(*found.Spec.Replicas).To(Equal(int32(3)))
+
+			By("scaling the Deployment directly")
+			found.Spec.Replicas = &[]int32{5}[0]
+			Expect(k8sClient.Update(ctx, found)).To(Succeed())
+			reconcileOnce()
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, found)).To(Succeed())
+			Expect(*found.Spec.Replicas).To(Equal(int32(3)))
+			Expect(recorder.Events).To(Receive(ContainSubstring("spec.replicas")))
+		})
+	})
+
+	Context("When reporting the observed state", func() {
+		const resourceName = "test-status"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			Expect(os.Setenv("SWXFLL_IMAGE", "example.com/image:test")).To(Succeed())
+
+			resource := &cachev1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: cachev1beta1.SwxfllSpec{
+					Size:       2,
+					Networking: &cachev1beta1.NetworkingSpec{Port: 11211},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &corev1.Pod{}, client.InNamespace("default"),
+				client.MatchingLabels(selectorLabelsForSwxfll(resourceName)))).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_IMAGE")).To(Succeed())
+		})
+
+		It("should report the replicas and the endpoints of the ready pods", func() {
+			controllerReconciler := &SwxfllReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+			reconcileOnce := func() {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			reconcileOnce()
+
+			By("simulating the pods of the Deployment")
+			for i, ip := range []string{"10.0.0.2", "10.0.0.1", "10.0.0.3"} {
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      fmt.Sprintf("%s-%d", resourceName, i),
+						Namespace: "default",
+						Labels:    selectorLabelsForSwxfll(resourceName),
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "swxfll", Image: "example.com/image:test"}},
+					},
+				}
+				Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+				pod.Status.PodIP = ip
+				pod.Status.Conditions = []corev1.PodCondition{{
+					Type:   corev1.PodReady,
+					Status: corev1.ConditionTrue,
+				}}
+				if i == 2 {
+					pod.Status.Conditions[0].Status = corev1.ConditionFalse
+				}
+				Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+			}
+
+			found := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, found)).To(Succeed())
+			found.Status.Replicas = 2
+			found.Status.ReadyReplicas = 2
+			Expect(k8sClient.Status().Update(ctx, found)).To(Succeed())
+			reconcileOnce()
+
+			swxfll := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			Expect(swxfll.Status.ObservedGeneration).To(Equal(swxfll.Generation))
+			Expect(swxfll.Status.Replicas).To(Equal(int32(2)))
+			Expect(swxfll.Status.ReadyReplicas).To(Equal(int32(2)))
+			Expect(swxfll.Status.Endpoints).To(Equal([]string{"10.0.0.1:11211", "10.0.0.2:11211"}))
+			Expect(swxfll.Status.LastReconcileTime).NotTo(BeNil())
+
+			By("publishing the server list in a ConfigMap")
+			Expect(swxfll.Status.ServerList).NotTo(BeNil())
+			Expect(swxfll.Status.ServerList.Servers).To(Equal(swxfll.Status.Endpoints))
+			cm := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      swxfll.Status.ServerList.ConfigMap,
+				Namespace: "default",
+			}, cm)).To(Succeed())
+			Expect(cm.Data).To(HaveKeyWithValue("servers", "10.0.0.1:11211\n10.0.0.2:11211\n"))
+			Expect(cm.Annotations).To(HaveKeyWithValue(serverListHashAnnotation, swxfll.Status.ServerList.Hash))
+		})
+	})
+
+	Context("When scraping memcached stats", func() {
+		const resourceName = "test-stats"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		var server *memcachedtest.Server
+
+		BeforeEach(func() {
+			Expect(os.Setenv("SWXFLL_IMAGE", "example.com/image:test")).To(Succeed())
+
+			var err error
+			server, err = memcachedtest.NewServer(map[string]string{
+				"get_hits":         "99",
+				"get_misses":       "1",
+				"evictions":        "5",
+				"curr_items":       "10",
+				"bytes":            "2048",
+				"limit_maxbytes":   "67108864",
+				"curr_connections": "8",
+			}, map[string]string{"maxconns": "1024"})
+			Expect(err).NotTo(HaveOccurred())
+
+			// 假服务器监听在 127.0.0.1 的随机端口上，Pod 的 IP 和 Swxfll 的端口都指向它
+			resource := &cachev1beta1.Swxfll{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: cachev1beta1.SwxfllSpec{
+					Size:       1,
+					Networking: &cachev1beta1.NetworkingSpec{Port: server.Port()},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName + "-0",
+					Namespace: "default",
+					Labels:    selectorLabelsForSwxfll(resourceName),
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "swxfll", Image: "example.com/image:test"}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+			pod.Status.PodIP = "127.0.0.1"
+			pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(server.Close()).To(Succeed())
+			resource := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &corev1.Pod{}, client.InNamespace("default"),
+				client.MatchingLabels(selectorLabelsForSwxfll(resourceName)))).To(Succeed())
+			Expect(os.Unsetenv("SWXFLL_IMAGE")).To(Succeed())
+		})
+
+		It("should summarize the stats of the ready pods in the status periodically", func() {
+			controllerReconciler := &SwxfllReconciler{
+				Client:        k8sClient,
+				Scheme:        k8sClient.Scheme(),
+				Recorder:      record.NewFakeRecorder(10),
+				StatsInterval: time.Minute,
+			}
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			// LastScrapeTime 精确到秒，等待时间可能比 StatsInterval 少不到一秒
+			Expect(result.RequeueAfter).To(BeNumerically("~", time.Minute, time.Second))
+
+			swxfll := &cachev1beta1.Swxfll{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			Expect(swxfll.Status.Stats).NotTo(BeNil())
+			Expect(swxfll.Status.Stats.Pods).To(Equal(int32(1)))
+			Expect(swxfll.Status.Stats.HitRatio).To(Equal("0.9900"))
+			Expect(swxfll.Status.Stats.Evictions).To(Equal(int64(5)))
+			Expect(swxfll.Status.Stats.CurrentConnections).To(Equal(int64(8)))
+			Expect(swxfll.Status.Stats.MaxConnections).To(Equal(int64(1024)))
+
+			By("keeping the stats on a reconcile before the interval has passed")
+			server.SetStat("evictions", "7")
+			scraped := swxfll.Status.Stats.LastScrapeTime
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			Expect(swxfll.Status.Stats.Evictions).To(Equal(int64(5)))
+			Expect(swxfll.Status.Stats.LastScrapeTime).To(Equal(scraped))
+
+			By("refreshing the stats once the interval has passed")
+			controllerReconciler.StatsInterval = time.Millisecond
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, swxfll)).To(Succeed())
+			Expect(swxfll.Status.Stats.Evictions).To(Equal(int64(7)))
+		})
+	})
 })
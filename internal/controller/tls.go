@@ -0,0 +1,518 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+	"github.com/swxfll/operator-sdk-demo/internal/memcached"
+)
+
+const (
+	// tlsVolumeName 是挂载证书 Secret 的卷名
+	tlsVolumeName = "tls"
+	// tlsMountPath 是 memcached 和 exporter 容器中证书 Secret 的挂载目录。
+	// 不使用 subPath，kubelet 才会在 Secret 更新后同步更新文件。
+	tlsMountPath = "/etc/memcached/tls"
+	// tlsSerialAnnotation 记录 Pod 使用的证书的序列号，用于在证书续期后滚动更新无法重新加载证书的 Pod
+	tlsSerialAnnotation = "cache.swxfll.com/tls-serial"
+	// tlsCAKey 是证书 Secret 中 CA 证书的键
+	tlsCAKey = "ca.crt"
+	// tlsNextCACertKey 和 tlsNextCAPrivateKeyKey 是 CA Secret 中已经发布到 ca.crt、还没有用于签发的下一个 CA 的键
+	tlsNextCACertKey       = "next.crt"
+	tlsNextCAPrivateKeyKey = "next.key"
+	// generatedCADuration 是 operator 生成的 CA 的有效期
+	generatedCADuration = 10 * 365 * 24 * time.Hour
+	// defaultRenewBefore 是未设置 Spec.TLS.RenewBefore 时在过期前多久续期生成的证书
+	defaultRenewBefore = 30 * 24 * time.Hour
+	// certificateReloadTimeout 是检查单个 Pod 的证书并让它重新加载的超时时间
+	certificateReloadTimeout = 2 * time.Second
+	// certificateReloadRecheck 是让 memcached 重新加载证书后再次检查的间隔，
+	// kubelet 同步挂载的 Secret 有延迟，重新加载的可能还是旧的证书
+	certificateReloadRecheck = 30 * time.Second
+)
+
+// tlsEnabled 返回是否为 swxfll 开启了 TLS
+func tlsEnabled(swxfll *cachev1beta1.Swxfll) bool {
+	return swxfll.Spec.TLS != nil && swxfll.Spec.TLS.Enabled
+}
+
+// tlsSecretNameForSwxfll 返回保存服务端证书的 Secret 名称，未引用用户的 Secret 时使用 operator 生成的 Secret
+func tlsSecretNameForSwxfll(swxfll *cachev1beta1.Swxfll) string {
+	if name := swxfll.Spec.TLS.SecretName; name != "" {
+		return name
+	}
+	return swxfll.Name + "-tls"
+}
+
+// tlsCASecretName 返回保存 operator 生成的 CA 证书和私钥的 Secret 名称
+func tlsCASecretName(name string) string {
+	return name + "-tls-ca"
+}
+
+// tlsServerName 返回生成的证书中客户端用于校验的名称，即 ClusterIP Service 的 DNS 名称
+func tlsServerName(swxfll *cachev1beta1.Swxfll) string {
+	return fmt.Sprintf("%s.%s.svc", swxfll.Name, swxfll.Namespace)
+}
+
+// tlsVerifyName 返回 Pod 中的容器校验证书时使用的名称，
+// 优先使用状态中记录的名称，因为用户提供的证书不一定包含 Service 的名称
+func tlsVerifyName(swxfll *cachev1beta1.Swxfll) string {
+	if swxfll.Status.TLS != nil && swxfll.Status.TLS.ServerName != "" {
+		return swxfll.Status.TLS.ServerName
+	}
+	return tlsServerName(swxfll)
+}
+
+// tlsDNSNames 返回生成的服务端证书包含的 DNS 名称：ClusterIP Service、headless Service，
+// 以及 StatefulSet Pod 通过 headless Service 解析的名称
+func tlsDNSNames(swxfll *cachev1beta1.Swxfll) []string {
+	var names []string
+	for _, host := range []string{swxfll.Name, headlessServiceName(swxfll.Name), "*." + headlessServiceName(swxfll.Name)} {
+		names = append(names,
+			host,
+			fmt.Sprintf("%s.%s", host, swxfll.Namespace),
+			fmt.Sprintf("%s.%s.svc", host, swxfll.Namespace),
+			fmt.Sprintf("%s.%s.svc.cluster.local", host, swxfll.Namespace),
+		)
+	}
+	return names
+}
+
+// tlsArgs 返回开启 TLS 的 memcached 参数，证书和私钥来自挂载的 Secret
+func tlsArgs() []string {
+	return []string{"-Z", "-o", fmt.Sprintf("ssl_chain_cert=%s/%s,ssl_key=%s/%s",
+		tlsMountPath, corev1.TLSCertKey, tlsMountPath, corev1.TLSPrivateKeyKey)}
+}
+
+// tlsVolumeForSwxfll 返回挂载证书 Secret 的卷
+func tlsVolumeForSwxfll(swxfll *cachev1beta1.Swxfll) corev1.Volume {
+	return corev1.Volume{Name: tlsVolumeName, VolumeSource: corev1.VolumeSource{
+		Secret: &corev1.SecretVolumeSource{SecretName: tlsSecretNameForSwxfll(swxfll)},
+	}}
+}
+
+// tlsState 是本次调和使用的证书，用于 operator 自己连接 memcached
+type tlsState struct {
+	// serverName 是校验证书时使用的名称
+	serverName string
+	// cert 是 Pod 应该提供的证书
+	cert *x509.Certificate
+	// roots 是 Secret 中 ca.crt 包含的 CA，Secret 中没有 ca.crt 时为空，使用系统的 CA
+	roots *x509.CertPool
+	// renewAt 是生成的证书需要续期的时间，用户的证书由用户负责续期，为零值
+	renewAt time.Time
+}
+
+// clientConfig 返回 operator 连接 memcached 使用的 TLS 配置
+func (s *tlsState) clientConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    s.roots,
+		ServerName: s.serverName,
+	}
+}
+
+// reconcileTLS 在开启 TLS 时读取用户的证书 Secret，或者生成并在过期前续期自签名的 CA 和服务端证书，
+// 并将证书的信息写入状态。关闭 TLS 时返回 nil，生成的 Secret 会被保留，重新开启时客户端信任的 CA 不会改变。
+func (r *SwxfllReconciler) reconcileTLS(ctx context.Context, swxfll *cachev1beta1.Swxfll, now time.Time) (*tlsState, error) {
+	if !tlsEnabled(swxfll) {
+		swxfll.Status.TLS = nil
+		return nil, nil
+	}
+
+	var state *tlsState
+	var caNotAfter *metav1.Time
+	var err error
+	if swxfll.Spec.TLS.SecretName != "" {
+		state, err = r.userTLSState(ctx, swxfll)
+	} else {
+		state, caNotAfter, err = r.generatedTLSState(ctx, swxfll, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	swxfll.Status.TLS = &cachev1beta1.TLSStatus{
+		SecretName:   tlsSecretNameForSwxfll(swxfll),
+		ServerName:   state.serverName,
+		SerialNumber: fmt.Sprintf("%x", state.cert.SerialNumber),
+		NotAfter:     metav1.NewTime(state.cert.NotAfter),
+		CANotAfter:   caNotAfter,
+	}
+	return state, nil
+}
+
+// userTLSState 读取用户提供的证书 Secret
+func (r *SwxfllReconciler) userTLSState(ctx context.Context, swxfll *cachev1beta1.Swxfll) (*tlsState, error) {
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Name: swxfll.Spec.TLS.SecretName, Namespace: swxfll.Namespace}
+	if err := r.Get(ctx, key, secret); err != nil {
+		return nil, fmt.Errorf("failed to get TLS Secret %s: %w", key.Name, err)
+	}
+	cert, _, err := parseKeyPair(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS Secret %s: %w", key.Name, err)
+	}
+
+	state := &tlsState{serverName: tlsServerName(swxfll), cert: cert}
+	// 用户的证书不一定包含 Service 的名称，此时使用证书中的第一个名称校验
+	if cert.VerifyHostname(state.serverName) != nil && len(cert.DNSNames) > 0 {
+		state.serverName = cert.DNSNames[0]
+	}
+	if ca, ok := secret.Data[tlsCAKey]; ok {
+		state.roots = x509.NewCertPool()
+		if !state.roots.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("invalid TLS Secret %s: no certificate found in %s", key.Name, tlsCAKey)
+		}
+	}
+	return state, nil
+}
+
+// generatedTLSState 返回 operator 生成的证书，CA 或服务端证书不存在、无效或即将过期时重新生成。
+// CA 在过期前 renewBefore 续期：新的 CA 先和旧的 CA 一起发布到 ca.crt，服务端证书仍然由旧的 CA 签发，
+// 到旧的 CA 过期前 renewBefore/2 时才切换到新的 CA 签发，客户端在这段时间内更新信任的 CA 不会中断连接。
+func (r *SwxfllReconciler) generatedTLSState(ctx context.Context, swxfll *cachev1beta1.Swxfll, now time.Time) (
+	*tlsState, *metav1.Time, error) {
+	renewBefore := defaultRenewBefore
+	if d := swxfll.Spec.TLS.RenewBefore; d != nil {
+		renewBefore = d.Duration
+	}
+
+	caSecret, err := r.getSecret(ctx, swxfll.Namespace, tlsCASecretName(swxfll.Name))
+	if err != nil {
+		return nil, nil, err
+	}
+	caSecret, err = r.rotateCA(ctx, swxfll, caSecret, renewBefore, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	caCert, caKey, err := parseKeyPair(caSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caSecret.Data[tlsCAKey])
+
+	dnsNames := tlsDNSNames(swxfll)
+	secret, err := r.getSecret(ctx, swxfll.Namespace, tlsSecretNameForSwxfll(swxfll))
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, _, err := parseKeyPair(secret)
+	reissue := err != nil || !now.Before(cert.NotAfter.Add(-renewBefore)) || cert.CheckSignatureFrom(caCert) != nil ||
+		!sameNames(cert.DNSNames, dnsNames)
+	if reissue || string(secret.Data[tlsCAKey]) != string(caSecret.Data[tlsCAKey]) {
+		// 只有 ca.crt 变化时保留原来的证书，只发布新的 CA
+		var certPEM, keyPEM []byte
+		if reissue {
+			if certPEM, keyPEM, err = issueServerCertificate(caCert, caKey, dnsNames, now); err != nil {
+				return nil, nil, err
+			}
+		} else {
+			certPEM, keyPEM = secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]
+		}
+		secret, err = r.tlsSecretForSwxfll(swxfll, tlsSecretNameForSwxfll(swxfll), certPEM, keyPEM, caSecret.Data[tlsCAKey])
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := r.apply(ctx, secret); err != nil {
+			return nil, nil, err
+		}
+		if reissue {
+			log.FromContext(ctx).Info("签发新的服务端证书", "Secret.Name", secret.Name)
+		}
+		if cert, _, err = parseKeyPair(secret); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	renewAt := cert.NotAfter.Add(-renewBefore)
+	caRenewAt := caCert.NotAfter.Add(-renewBefore)
+	if hasNextCA(caSecret) {
+		caRenewAt = caCert.NotAfter.Add(-renewBefore / 2)
+	}
+	if caRenewAt.Before(renewAt) {
+		renewAt = caRenewAt
+	}
+	caNotAfter := metav1.NewTime(caCert.NotAfter)
+	return &tlsState{serverName: tlsServerName(swxfll), cert: cert, roots: roots, renewAt: renewAt}, &caNotAfter, nil
+}
+
+// rotateCA 返回签发服务端证书的 CA 所在的 Secret，按需生成或轮换 CA：
+//   - 当前的 CA 在 renewBefore/2 内过期时切换到已经发布的下一个 CA，ca.crt 保持不变，旧的 CA 过期前客户端仍然可以信任它
+//   - 没有有效的 CA 时生成新的 CA 并立即使用
+//   - CA 在 renewBefore 内过期时生成下一个 CA，和当前的 CA 一起发布到 ca.crt，仍然使用当前的 CA 签发
+func (r *SwxfllReconciler) rotateCA(ctx context.Context, swxfll *cachev1beta1.Swxfll, caSecret *corev1.Secret,
+	renewBefore time.Duration, now time.Time) (*corev1.Secret, error) {
+	caCert, _, err := parseKeyPair(caSecret)
+	hasNext := hasNextCA(caSecret)
+
+	var certPEM, keyPEM, caPEM, nextCertPEM, nextKeyPEM []byte
+	switch {
+	case hasNext && (err != nil || !now.Before(caCert.NotAfter.Add(-renewBefore/2))):
+		certPEM, keyPEM = caSecret.Data[tlsNextCACertKey], caSecret.Data[tlsNextCAPrivateKeyKey]
+		caPEM = caSecret.Data[tlsCAKey]
+		log.FromContext(ctx).Info("切换到新的 CA 签发服务端证书", "Secret.Name", caSecret.Name)
+	case err != nil || !now.Before(caCert.NotAfter):
+		if certPEM, keyPEM, err = generateCA(swxfll.Name, now); err != nil {
+			return nil, err
+		}
+		caPEM = certPEM
+		log.FromContext(ctx).Info("生成新的 CA", "Secret.Name", tlsCASecretName(swxfll.Name))
+	case !hasNext && !now.Before(caCert.NotAfter.Add(-renewBefore)):
+		if nextCertPEM, nextKeyPEM, err = generateCA(swxfll.Name, now); err != nil {
+			return nil, err
+		}
+		certPEM, keyPEM = caSecret.Data[corev1.TLSCertKey], caSecret.Data[corev1.TLSPrivateKeyKey]
+		caPEM = append(append([]byte{}, nextCertPEM...), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw})...)
+		log.FromContext(ctx).Info("发布新的 CA", "Secret.Name", caSecret.Name)
+	default:
+		return caSecret, nil
+	}
+
+	secret, err := r.tlsSecretForSwxfll(swxfll, tlsCASecretName(swxfll.Name), certPEM, keyPEM, caPEM)
+	if err != nil {
+		return nil, err
+	}
+	if nextCertPEM != nil {
+		secret.Data[tlsNextCACertKey] = nextCertPEM
+		secret.Data[tlsNextCAPrivateKeyKey] = nextKeyPEM
+	}
+	if err := r.apply(ctx, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// hasNextCA 返回 CA Secret 中是否有已经发布、还没有用于签发的有效的下一个 CA
+func hasNextCA(caSecret *corev1.Secret) bool {
+	if caSecret == nil || len(caSecret.Data[tlsNextCACertKey]) == 0 {
+		return false
+	}
+	_, _, err := parseKeyPair(&corev1.Secret{Data: map[string][]byte{
+		corev1.TLSCertKey:       caSecret.Data[tlsNextCACertKey],
+		corev1.TLSPrivateKeyKey: caSecret.Data[tlsNextCAPrivateKeyKey],
+	}})
+	return err == nil
+}
+
+// getSecret 返回 Secret，不存在时返回 nil
+func (r *SwxfllReconciler) getSecret(ctx context.Context, namespace, name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return secret, nil
+}
+
+// tlsSecretForSwxfll 返回 operator 生成的 kubernetes.io/tls 类型的 Secret 对象
+func (r *SwxfllReconciler) tlsSecretForSwxfll(swxfll *cachev1beta1.Swxfll, name string, certPEM, keyPEM, caPEM []byte) (
+	*corev1.Secret, error) {
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: swxfll.Namespace,
+			Labels:    selectorLabelsForSwxfll(swxfll.Name),
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+			tlsCAKey:                caPEM,
+		},
+	}
+
+	if err := ctrl.SetControllerReference(swxfll, secret, r.Scheme); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// parseKeyPair 解析 Secret 中的 tls.crt 和 tls.key，返回第一个证书和私钥
+func parseKeyPair(secret *corev1.Secret) (*x509.Certificate, crypto.Signer, error) {
+	if secret == nil {
+		return nil, nil, fmt.Errorf("secret not found")
+	}
+	pair, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported private key type %T", pair.PrivateKey)
+	}
+	return cert, key, nil
+}
+
+// generateCA 生成自签名的 CA 证书和私钥，以 PEM 编码返回
+func generateCA(name string, now time.Time) ([]byte, []byte, error) {
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name + "-ca", Organization: []string{"swxfll-operator"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(generatedCADuration),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	return createCertificate(template, nil, nil)
+}
+
+// issueServerCertificate 用 CA 签发包含 dnsNames 的服务端证书，以 PEM 编码返回
+func issueServerCertificate(ca *x509.Certificate, caKey crypto.Signer, dnsNames []string, now time.Time) ([]byte, []byte, error) {
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: dnsNames[0], Organization: []string{"swxfll-operator"}},
+		DNSNames:    dnsNames,
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.Add(cachev1beta1.GeneratedCertificateDuration),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	return createCertificate(template, ca, caKey)
+}
+
+// createCertificate 为 template 生成 ECDSA P-256 私钥并签发证书，parent 为空时自签名
+func createCertificate(template, parent *x509.Certificate, parentKey crypto.Signer) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	template.SerialNumber = serial
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+// sameNames 返回两组名称是否相同，忽略顺序
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return strings.Join(a, ",") == strings.Join(b, ",")
+}
+
+// reloadCertificates 检查每个就绪 Pod 提供的证书，与 Secret 中的证书不同时让 memcached 重新加载证书文件，
+// 返回是否有 Pod 重新加载了证书，此时需要稍后再次检查。
+// memcached 不会自己发现挂载的 Secret 已经更新，这样证书续期后无需重启 Pod，缓存的数据不会丢失。
+func (r *SwxfllReconciler) reloadCertificates(ctx context.Context, endpoints []string, state *tlsState) bool {
+	log := log.FromContext(ctx)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	anyReloaded := false
+	for _, endpoint := range endpoints {
+		wg.Add(1)
+		go func(endpoint string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, certificateReloadTimeout)
+			defer cancel()
+
+			reloaded, err := memcached.ReloadCertificate(ctx, endpoint, state.clientConfig(), state.cert)
+			if err != nil {
+				log.V(1).Info("无法检查 memcached 的证书", "endpoint", endpoint, "error", err.Error())
+				return
+			}
+			if reloaded {
+				log.Info("memcached 重新加载了证书", "endpoint", endpoint)
+				mu.Lock()
+				anyReloaded = true
+				mu.Unlock()
+			}
+		}(endpoint)
+	}
+	wg.Wait()
+	return anyReloaded
+}
+
+// swxfllsForSecret 将 Secret 的事件映射为使用它的 Swxfll 的调和请求，
+// 用户的证书被续期（例如由 cert-manager）或凭据被修改后会立即生效
+func (r *SwxfllReconciler) swxfllsForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := &cachev1beta1.SwxfllList{}
+	if err := r.List(ctx, list, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list swxflls for Secret", "Secret.Name", obj.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range list.Items {
+		swxfll := &list.Items[i]
+		if secretUsedBySwxfll(swxfll, obj.GetName()) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(swxfll)})
+		}
+	}
+	return requests
+}
+
+// secretUsedBySwxfll 返回名为 name 的 Secret 是否是 swxfll 使用的证书或凭据
+func secretUsedBySwxfll(swxfll *cachev1beta1.Swxfll, name string) bool {
+	if tlsEnabled(swxfll) && (name == tlsSecretNameForSwxfll(swxfll) || name == tlsCASecretName(swxfll.Name)) {
+		return true
+	}
+	return authEnabled(swxfll) && (name == authSecretNameForSwxfll(swxfll) || name == saslSecretName(swxfll.Name))
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+	"github.com/swxfll/operator-sdk-demo/internal/memcached/memcachedtest"
+)
+
+var _ = Describe("TLS", func() {
+	newSwxfll := func() *cachev1beta1.Swxfll {
+		return &cachev1beta1.Swxfll{
+			ObjectMeta: metav1.ObjectMeta{Name: "sessions", Namespace: "cache"},
+			Spec: cachev1beta1.SwxfllSpec{
+				Size: 1,
+				TLS:  &cachev1beta1.TLSSpec{Enabled: true},
+			},
+		}
+	}
+
+	// newCA 生成一个 CA，返回 CA 证书、私钥以及信任它的证书池
+	newCA := func(swxfll *cachev1beta1.Swxfll) (*x509.Certificate, crypto.Signer, *x509.CertPool) {
+		caPEM, caKeyPEM, err := generateCA(swxfll.Name, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		ca, caKey, err := parseKeyPair(&corev1.Secret{Data: map[string][]byte{
+			corev1.TLSCertKey: caPEM, corev1.TLSPrivateKeyKey: caKeyPEM,
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		roots := x509.NewCertPool()
+		roots.AddCert(ca)
+		return ca, caKey, roots
+	}
+
+	// issue 用 CA 签发 swxfll 的服务端证书
+	issue := func(swxfll *cachev1beta1.Swxfll, ca *x509.Certificate, caKey crypto.Signer) tls.Certificate {
+		certPEM, keyPEM, err := issueServerCertificate(ca, caKey, tlsDNSNames(swxfll), time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		Expect(err).NotTo(HaveOccurred())
+		cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+		Expect(err).NotTo(HaveOccurred())
+		return cert
+	}
+
+	It("should issue a certificate valid for the Services and the StatefulSet pods", func() {
+		swxfll := newSwxfll()
+		ca, caKey, roots := newCA(swxfll)
+		cert := issue(swxfll, ca, caKey)
+
+		for _, name := range []string{tlsServerName(swxfll), "sessions-headless.cache.svc", "sessions-0.sessions-headless.cache.svc"} {
+			_, err := cert.Leaf.Verify(x509.VerifyOptions{DNSName: name, Roots: roots})
+			Expect(err).NotTo(HaveOccurred(), name)
+		}
+		Expect(cert.Leaf.NotAfter).To(BeTemporally("~", time.Now().Add(cachev1beta1.GeneratedCertificateDuration), time.Minute))
+	})
+
+	It("should start memcached with TLS using the mounted Secret", func() {
+		Expect(os.Setenv("SWXFLL_IMAGE", "example.com/image:test")).To(Succeed())
+		DeferCleanup(os.Unsetenv, "SWXFLL_IMAGE")
+
+		scheme := runtime.NewScheme()
+		Expect(cachev1beta1.AddToScheme(scheme)).To(Succeed())
+		r := &SwxfllReconciler{Scheme: scheme}
+
+		dep, err := r.deploymentForSwxfll(newSwxfll())
+		Expect(err).NotTo(HaveOccurred())
+		spec := dep.Spec.Template.Spec
+		Expect(spec.Containers[0].Args).To(ContainElements("-Z",
+			"ssl_chain_cert=/etc/memcached/tls/tls.crt,ssl_key=/etc/memcached/tls/tls.key"))
+		Expect(spec.Containers[0].VolumeMounts).To(ContainElement(HaveField("MountPath", tlsMountPath)))
+		Expect(spec.Volumes).To(ContainElement(HaveField("Secret.SecretName", "sessions-tls")))
+
+		withSecret := newSwxfll()
+		withSecret.Spec.TLS.SecretName = "sessions-cert"
+		dep, err = r.deploymentForSwxfll(withSecret)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Spec.Template.Spec.Volumes).To(ContainElement(HaveField("Secret.SecretName", "sessions-cert")))
+	})
+
+	It("should make memcached reload a renewed certificate", func() {
+		swxfll := newSwxfll()
+		ca, caKey, roots := newCA(swxfll)
+		cert := issue(swxfll, ca, caKey)
+		server, err := memcachedtest.NewTLSServer(nil, nil, cert)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(server.Close)
+
+		r := &SwxfllReconciler{}
+		state := &tlsState{serverName: tlsServerName(swxfll), cert: cert.Leaf, roots: roots}
+		Expect(r.reloadCertificates(context.Background(), []string{server.Addr}, state)).To(BeFalse())
+
+		By("renewing the certificate in the Secret")
+		renewed := issue(swxfll, ca, caKey)
+		server.SetCertificate(renewed)
+		state.cert = renewed.Leaf
+		Expect(r.reloadCertificates(context.Background(), []string{server.Addr}, state)).To(BeTrue())
+		Expect(server.CertRefreshes()).To(Equal(1))
+		Expect(r.reloadCertificates(context.Background(), []string{server.Addr}, state)).To(BeFalse())
+	})
+
+	It("should keep serving a certificate trusted by the old CA until it cuts over to a renewed CA", func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(cachev1beta1.AddToScheme(scheme)).To(Succeed())
+		swxfll := newSwxfll()
+		// 假的客户端不支持 server-side apply，operator 是 Secret 唯一的管理者，用创建或整体更新代替
+		apply := func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			if err := c.Update(ctx, obj); !apierrors.IsNotFound(err) {
+				return err
+			}
+			return c.Create(ctx, obj)
+		}
+		r := &SwxfllReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(swxfll).
+				WithInterceptorFuncs(interceptor.Funcs{Patch: apply}).Build(),
+			Scheme: scheme,
+		}
+		ctx := context.Background()
+
+		// verify 用 pem 编码的 CA 校验 state 中的服务端证书
+		verify := func(state *tlsState, caPEM []byte, now time.Time) error {
+			roots := x509.NewCertPool()
+			Expect(roots.AppendCertsFromPEM(caPEM)).To(BeTrue())
+			_, err := state.cert.Verify(x509.VerifyOptions{DNSName: state.serverName, Roots: roots, CurrentTime: now})
+			return err
+		}
+		published := func() []byte {
+			secret := &corev1.Secret{}
+			Expect(r.Get(ctx, client.ObjectKey{Name: "sessions-tls", Namespace: "cache"}, secret)).To(Succeed())
+			return secret.Data[tlsCAKey]
+		}
+
+		now := time.Now()
+		state, _, err := r.generatedTLSState(ctx, swxfll, now)
+		Expect(err).NotTo(HaveOccurred())
+		oldCA := published()
+		Expect(verify(state, oldCA, now)).To(Succeed())
+
+		By("publishing the renewed CA while the old CA keeps signing")
+		caNotAfter := func() time.Time {
+			secret := &corev1.Secret{}
+			Expect(r.Get(ctx, client.ObjectKey{Name: "sessions-tls-ca", Namespace: "cache"}, secret)).To(Succeed())
+			ca, _, err := parseKeyPair(secret)
+			Expect(err).NotTo(HaveOccurred())
+			return ca.NotAfter
+		}
+		oldNotAfter := caNotAfter()
+		now = oldNotAfter.Add(-defaultRenewBefore + time.Hour)
+		state, _, err = r.generatedTLSState(ctx, swxfll, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verify(state, oldCA, now)).To(Succeed())
+		bundle := published()
+		Expect(bundle).NotTo(Equal(oldCA))
+		Expect(state.renewAt).To(Equal(oldNotAfter.Add(-defaultRenewBefore / 2)))
+
+		By("cutting over to the renewed CA within renewBefore/2 of the old CA expiry")
+		now = oldNotAfter.Add(-defaultRenewBefore/2 + time.Hour)
+		state, _, err = r.generatedTLSState(ctx, swxfll, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verify(state, oldCA, now)).NotTo(Succeed())
+		Expect(verify(state, bundle, now)).To(Succeed())
+		Expect(caNotAfter()).To(BeTemporally(">", oldNotAfter))
+		Expect(published()).To(Equal(bundle))
+	})
+
+	It("should map the metadata of the referenced Secrets to the Swxfll using them", func() {
+		scheme := runtime.NewScheme()
+		Expect(cachev1beta1.AddToScheme(scheme)).To(Succeed())
+		swxfll := newSwxfll()
+		swxfll.Spec.TLS = &cachev1beta1.TLSSpec{Enabled: true, SecretName: "sessions-cert"}
+		swxfll.Spec.Auth = &cachev1beta1.AuthSpec{Enabled: true}
+		r := &SwxfllReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(swxfll).Build(), Scheme: scheme}
+
+		secret := func(name string) client.Object {
+			return &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "cache"}}
+		}
+		want := []reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(swxfll)}}
+		Expect(r.swxfllsForSecret(context.Background(), secret("sessions-cert"))).To(Equal(want))
+		Expect(r.swxfllsForSecret(context.Background(), secret(saslSecretName("sessions")))).To(Equal(want))
+		Expect(r.swxfllsForSecret(context.Background(), secret(tlsSecretNameForSwxfll(newSwxfll())))).To(BeEmpty())
+	})
+})
@@ -0,0 +1,166 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+// workloadTypeForSwxfll 返回运行实例的工作负载类型，未设置 Spec.WorkloadType 时为 Deployment
+func workloadTypeForSwxfll(swxfll *cachev1beta1.Swxfll) cachev1beta1.WorkloadType {
+	if swxfll.Spec.WorkloadType == cachev1beta1.WorkloadTypeStatefulSet {
+		return cachev1beta1.WorkloadTypeStatefulSet
+	}
+	return cachev1beta1.WorkloadTypeDeployment
+}
+
+// newWorkload 返回指定类型的空对象，用于从集群中读取工作负载
+func newWorkload(workloadType cachev1beta1.WorkloadType) client.Object {
+	if workloadType == cachev1beta1.WorkloadTypeStatefulSet {
+		return &appsv1.StatefulSet{}
+	}
+	return &appsv1.Deployment{}
+}
+
+// otherWorkloadType 返回迁移时需要清理的另一种工作负载类型
+func otherWorkloadType(workloadType cachev1beta1.WorkloadType) cachev1beta1.WorkloadType {
+	if workloadType == cachev1beta1.WorkloadTypeStatefulSet {
+		return cachev1beta1.WorkloadTypeDeployment
+	}
+	return cachev1beta1.WorkloadTypeStatefulSet
+}
+
+// workloadForSwxfll 根据 Spec.WorkloadType 返回期望的 Deployment 或 StatefulSet
+func (r *SwxfllReconciler) workloadForSwxfll(swxfll *cachev1beta1.Swxfll) (client.Object, error) {
+	if workloadTypeForSwxfll(swxfll) == cachev1beta1.WorkloadTypeStatefulSet {
+		return r.statefulSetForSwxfll(swxfll)
+	}
+	return r.deploymentForSwxfll(swxfll)
+}
+
+// statefulSetForSwxfll 返回一个 Swxfll StatefulSet 对象。
+// Pod 模板与 Deployment 相同，Pod 的名称 name-0..N 以及它们在 headless Service 下的 DNS 记录在重建后保持不变，
+// 使用一致性哈希的客户端不会因为 Pod 被替换而重新映射 key。
+func (r *SwxfllReconciler) statefulSetForSwxfll(swxfll *cachev1beta1.Swxfll) (*appsv1.StatefulSet, error) {
+	dep, err := r.deploymentForSwxfll(swxfll)
+	if err != nil {
+		return nil, err
+	}
+
+	return &appsv1.StatefulSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "StatefulSet",
+		},
+		// 包含 deploymentForSwxfll 设置的 ownerRef
+		ObjectMeta: dep.ObjectMeta,
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    dep.Spec.Replicas,
+			Selector:    dep.Spec.Selector,
+			Template:    dep.Spec.Template,
+			ServiceName: headlessServiceName(swxfll.Name),
+			// 缓存实例之间没有启动顺序的依赖，并行创建和删除 Pod 可以加快扩缩容
+			PodManagementPolicy: appsv1.ParallelPodManagement,
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type: appsv1.RollingUpdateStatefulSetStrategyType,
+			},
+		},
+	}, nil
+}
+
+// podTemplateOf 返回 Deployment 或 StatefulSet 的 Pod 模板
+func podTemplateOf(obj client.Object) *corev1.PodTemplateSpec {
+	switch w := obj.(type) {
+	case *appsv1.Deployment:
+		return &w.Spec.Template
+	case *appsv1.StatefulSet:
+		return &w.Spec.Template
+	}
+	return &corev1.PodTemplateSpec{}
+}
+
+// replicasOf 返回 Deployment 或 StatefulSet 的副本数
+func replicasOf(obj client.Object) *int32 {
+	switch w := obj.(type) {
+	case *appsv1.Deployment:
+		return w.Spec.Replicas
+	case *appsv1.StatefulSet:
+		return w.Spec.Replicas
+	}
+	return nil
+}
+
+// workloadNeedsRecreate 判断 found 的不可变字段是否与期望不一致，这种情况下无法原地更新，只能删除后重新创建。
+// 旧版本 operator 创建的工作负载的 selector 可能与期望不一致；StatefulSet 的 serviceName 和 podManagementPolicy 也不可变。
+func workloadNeedsRecreate(desired, found client.Object) bool {
+	switch want := desired.(type) {
+	case *appsv1.Deployment:
+		got := found.(*appsv1.Deployment)
+		return !equality.Semantic.DeepEqual(got.Spec.Selector, want.Spec.Selector)
+	case *appsv1.StatefulSet:
+		got := found.(*appsv1.StatefulSet)
+		return !equality.Semantic.DeepEqual(got.Spec.Selector, want.Spec.Selector) ||
+			got.Spec.ServiceName != want.Spec.ServiceName ||
+			got.Spec.PodManagementPolicy != want.Spec.PodManagementPolicy
+	}
+	return false
+}
+
+// migrateWorkload 在 Spec.WorkloadType 变化后清理另一种类型的工作负载。
+// 为了避免缓存完全不可用，旧的工作负载会一直保留到新的工作负载滚动完成并且全部可用，
+// 在此期间两者的 Pod 都被 Service 选中。返回 true 表示迁移仍在进行中。
+func (r *SwxfllReconciler) migrateWorkload(ctx context.Context, swxfll *cachev1beta1.Swxfll, rollout workloadRollout) (bool, error) {
+	log := log.FromContext(ctx)
+
+	oldType := otherWorkloadType(workloadTypeForSwxfll(swxfll))
+	old := newWorkload(oldType)
+	if err := r.Get(ctx, types.NamespacedName{Name: swxfll.Name, Namespace: swxfll.Namespace}, old); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	// 只清理由该 swxfll 创建的工作负载
+	if !metav1.IsControlledBy(old, swxfll) {
+		return false, nil
+	}
+	if !rollout.complete() {
+		log.Info("等待新的工作负载可用后再删除旧的工作负载",
+			"from", oldType, "to", rollout.kind, "Swxfll.Namespace", swxfll.Namespace, "Swxfll.Name", swxfll.Name)
+		return true, nil
+	}
+
+	if err := r.Delete(ctx, old, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil &&
+		!apierrors.IsNotFound(err) {
+		return false, err
+	}
+	r.Recorder.Event(swxfll, "Normal", "Migrated",
+		fmt.Sprintf("Migrated the instances from %s to %s %s/%s", oldType, rollout.kind, swxfll.Namespace, swxfll.Name))
+	return false, nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memcached
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 二进制协议的 magic 和 opcode，参见 https://github.com/memcached/memcached/wiki/BinaryProtocolRevamped
+const (
+	binaryRequestMagic  = 0x80
+	binaryResponseMagic = 0x81
+	binaryHeaderLength  = 24
+
+	opcodeStat     = 0x10
+	opcodeSASLAuth = 0x21
+
+	statusSuccess   = 0x0000
+	statusAuthError = 0x0020
+)
+
+// Credentials 是通过 SASL PLAIN 认证使用的用户名和密码
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// binaryPacket 是二进制协议的一个请求或响应，只包含这里用到的字段
+type binaryPacket struct {
+	status uint16
+	key    []byte
+	value  []byte
+}
+
+// writeBinaryRequest 写入一个没有 extras 的二进制协议请求
+func writeBinaryRequest(w io.Writer, opcode byte, key, value []byte) error {
+	header := make([]byte, binaryHeaderLength)
+	header[0] = binaryRequestMagic
+	header[1] = opcode
+	binary.BigEndian.PutUint16(header[2:4], uint16(len(key)))
+	binary.BigEndian.PutUint32(header[8:12], uint32(len(key)+len(value)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(key); err != nil {
+		return err
+	}
+	_, err := w.Write(value)
+	return err
+}
+
+// readBinaryResponse 读取一个二进制协议响应，跳过 extras
+func readBinaryResponse(r io.Reader) (*binaryPacket, error) {
+	header := make([]byte, binaryHeaderLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[0] != binaryResponseMagic {
+		return nil, fmt.Errorf("unexpected magic 0x%x in binary response", header[0])
+	}
+	keyLength := int(binary.BigEndian.Uint16(header[2:4]))
+	extrasLength := int(header[4])
+	bodyLength := int(binary.BigEndian.Uint32(header[8:12]))
+	if keyLength+extrasLength > bodyLength {
+		return nil, fmt.Errorf("malformed binary response: body of %d bytes is shorter than its key and extras", bodyLength)
+	}
+	body := make([]byte, bodyLength)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return &binaryPacket{
+		status: binary.BigEndian.Uint16(header[6:8]),
+		key:    body[extrasLength : extrasLength+keyLength],
+		value:  body[extrasLength+keyLength:],
+	}, nil
+}
+
+// authenticate 通过 SASL PLAIN 机制认证连接
+func authenticate(rw *bufio.ReadWriter, credentials *Credentials) error {
+	token := "\x00" + credentials.Username + "\x00" + credentials.Password
+	if err := writeBinaryRequest(rw, opcodeSASLAuth, []byte("PLAIN"), []byte(token)); err != nil {
+		return err
+	}
+	if err := rw.Flush(); err != nil {
+		return err
+	}
+	resp, err := readBinaryResponse(rw)
+	if err != nil {
+		return err
+	}
+	switch resp.status {
+	case statusSuccess:
+		return nil
+	case statusAuthError:
+		return fmt.Errorf("SASL authentication as %q failed", credentials.Username)
+	default:
+		return fmt.Errorf("SASL authentication failed with status 0x%x: %s", resp.status, resp.value)
+	}
+}
+
+// runBinaryStats 通过二进制协议执行 stats 命令，command 是文本协议中的命令，
+// 例如 "stats settings" 对应 key 为 settings 的 stat 请求。memcached 以一个 key 为空的响应结束统计值。
+func runBinaryStats(rw *bufio.ReadWriter, command string) (map[string]string, error) {
+	group := strings.TrimSpace(strings.TrimPrefix(command, "stats"))
+	if err := writeBinaryRequest(rw, opcodeStat, []byte(group), nil); err != nil {
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for {
+		resp, err := readBinaryResponse(rw)
+		if err != nil {
+			return nil, err
+		}
+		if resp.status != statusSuccess {
+			return nil, fmt.Errorf("%q failed with status 0x%x: %s", command, resp.status, resp.value)
+		}
+		if len(resp.key) == 0 {
+			return values, nil
+		}
+		values[string(resp.key)] = string(resp.value)
+	}
+}
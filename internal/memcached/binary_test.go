@@ -0,0 +1,65 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memcached
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/swxfll/operator-sdk-demo/internal/memcached/memcachedtest"
+)
+
+var _ = Describe("SASL", func() {
+	var server *memcachedtest.Server
+
+	BeforeEach(func() {
+		var err error
+		server, err = memcachedtest.NewServer(map[string]string{"get_hits": "5", "curr_items": "2"},
+			map[string]string{"maxconns": "1024"})
+		Expect(err).NotTo(HaveOccurred())
+		server.SetCredentials(map[string]string{"cache": "current", "old": "previous"})
+	})
+
+	AfterEach(func() {
+		Expect(server.Close()).To(Succeed())
+	})
+
+	It("should fetch stats over the binary protocol after authenticating", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		stats, err := FetchStats(ctx, server.Addr, nil, &Credentials{Username: "cache", Password: "current"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats).To(Equal(&Stats{GetHits: 5, CurrItems: 2, MaxConnections: 1024}))
+
+		_, err = FetchStats(ctx, server.Addr, nil, &Credentials{Username: "old", Password: "previous"})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should report rejected credentials", func() {
+		_, err := FetchStats(context.Background(), server.Addr, nil, &Credentials{Username: "cache", Password: "wrong"})
+		Expect(err).To(MatchError(ContainSubstring("SASL authentication")))
+	})
+
+	It("should not be readable over the text protocol", func() {
+		_, err := FetchStats(context.Background(), server.Addr, nil, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,300 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package memcachedtest 提供一个只实现 stats 命令的假 memcached 服务器，用于离线测试
+package memcachedtest
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Server 是监听在 127.0.0.1 随机端口上的假 memcached 服务器
+type Server struct {
+	// Addr 是服务器监听的 host:port
+	Addr string
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	stats    map[string]string
+	settings map[string]string
+	conns    map[net.Conn]struct{}
+
+	// cert 是 TLS 握手时提供的证书，pending 是 refresh_certs 时加载的证书，相当于磁盘上的证书文件
+	cert      *tls.Certificate
+	pending   *tls.Certificate
+	refreshes int
+
+	// credentials 不为空时与开启 -S 的 memcached 一样只接受通过 SASL 认证的二进制协议连接
+	credentials map[string]string
+}
+
+// NewServer 启动一个假 memcached 服务器，stats 和 settings 分别是 stats 和 stats settings 命令返回的值
+func NewServer(stats, settings map[string]string) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := newServer(stats, settings)
+	s.Addr, s.listener = listener.Addr().String(), listener
+	s.start()
+	return s, nil
+}
+
+// NewTLSServer 启动一个只接受 TLS 连接的假 memcached 服务器，握手时提供 cert，
+// 与开启 -Z 的 memcached 一样支持 refresh_certs 命令
+func NewTLSServer(stats, settings map[string]string, cert tls.Certificate) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := newServer(stats, settings)
+	s.cert, s.pending = &cert, &cert
+	s.Addr = listener.Addr().String()
+	s.listener = tls.NewListener(listener, &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return s.cert, nil
+		},
+	})
+	s.start()
+	return s, nil
+}
+
+func newServer(stats, settings map[string]string) *Server {
+	s := &Server{
+		stats:    map[string]string{},
+		settings: map[string]string{},
+		conns:    map[net.Conn]struct{}{},
+	}
+	for k, v := range stats {
+		s.stats[k] = v
+	}
+	for k, v := range settings {
+		s.settings[k] = v
+	}
+	return s
+}
+
+// Port 返回服务器监听的端口
+func (s *Server) Port() int32 {
+	return int32(s.listener.Addr().(*net.TCPAddr).Port)
+}
+
+// SetCertificate 替换下一次 refresh_certs 时加载的证书，相当于更新磁盘上的证书文件
+func (s *Server) SetCertificate(cert tls.Certificate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = &cert
+}
+
+// SetCredentials 设置 SASL PLAIN 认证接受的用户名和密码，之后服务器不再接受文本协议
+func (s *Server) SetCredentials(credentials map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.credentials = credentials
+}
+
+// CertRefreshes 返回服务器收到的 refresh_certs 命令数
+func (s *Server) CertRefreshes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refreshes
+}
+
+// SetStat 修改 stats 命令返回的一项统计值
+func (s *Server) SetStat(name, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats[name] = value
+}
+
+// Close 停止服务器，关闭所有连接并等待它们处理结束
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) start() {
+	s.wg.Add(1)
+	go s.serve()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	if first, err := rw.Peek(1); err == nil && first[0] == 0x80 {
+		s.handleBinary(rw)
+		return
+	}
+	s.mu.Lock()
+	sasl := s.credentials != nil
+	s.mu.Unlock()
+	if sasl {
+		fmt.Fprint(rw, "ERROR\r\n")
+		rw.Flush()
+		return
+	}
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch strings.TrimSpace(line) {
+		case "stats":
+			s.writeStats(rw.Writer, s.stats)
+		case "stats settings":
+			s.writeStats(rw.Writer, s.settings)
+		case "refresh_certs":
+			s.mu.Lock()
+			s.refreshes++
+			s.cert = s.pending
+			s.mu.Unlock()
+			fmt.Fprint(rw, "OK\r\n")
+		case "quit":
+			return
+		default:
+			fmt.Fprint(rw, "ERROR\r\n")
+		}
+		if err := rw.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) writeStats(w *bufio.Writer, values map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "STAT %s %s\r\n", name, values[name])
+	}
+	fmt.Fprint(w, "END\r\n")
+}
+
+// handleBinary 处理二进制协议的 SASL 认证和 stat 请求
+func (s *Server) handleBinary(rw *bufio.ReadWriter) {
+	authenticated := false
+	for {
+		header := make([]byte, 24)
+		if _, err := io.ReadFull(rw, header); err != nil {
+			return
+		}
+		opcode := header[1]
+		keyLength := int(binary.BigEndian.Uint16(header[2:4]))
+		extrasLength := int(header[4])
+		body := make([]byte, binary.BigEndian.Uint32(header[8:12]))
+		if _, err := io.ReadFull(rw, body); err != nil {
+			return
+		}
+		key := string(body[extrasLength : extrasLength+keyLength])
+		value := string(body[extrasLength+keyLength:])
+
+		s.mu.Lock()
+		credentials := s.credentials
+		s.mu.Unlock()
+		switch {
+		case opcode == 0x21:
+			// PLAIN 的 token 是 authzid NUL authcid NUL passwd
+			fields := strings.Split(value, "\x00")
+			if key == "PLAIN" && len(fields) == 3 && credentials != nil && credentials[fields[1]] == fields[2] {
+				authenticated = true
+				writeBinaryResponse(rw, opcode, 0x0000, "", "Authenticated")
+			} else {
+				writeBinaryResponse(rw, opcode, 0x0020, "", "Auth failure")
+			}
+		case credentials != nil && !authenticated:
+			writeBinaryResponse(rw, opcode, 0x0020, "", "Auth failure")
+		case opcode == 0x10:
+			values := s.stats
+			if key == "settings" {
+				values = s.settings
+			}
+			s.mu.Lock()
+			names := make([]string, 0, len(values))
+			for name := range values {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				writeBinaryResponse(rw, opcode, 0x0000, name, values[name])
+			}
+			s.mu.Unlock()
+			writeBinaryResponse(rw, opcode, 0x0000, "", "")
+		default:
+			writeBinaryResponse(rw, opcode, 0x0081, "", "Unknown command")
+		}
+		if err := rw.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeBinaryResponse 写入一个没有 extras 的二进制协议响应
+func writeBinaryResponse(w io.Writer, opcode byte, status uint16, key, value string) {
+	header := make([]byte, 24)
+	header[0] = 0x81
+	header[1] = opcode
+	binary.BigEndian.PutUint16(header[2:4], uint16(len(key)))
+	binary.BigEndian.PutUint16(header[6:8], status)
+	binary.BigEndian.PutUint32(header[8:12], uint32(len(key)+len(value)))
+	w.Write(header)
+	io.WriteString(w, key)
+	io.WriteString(w, value)
+}
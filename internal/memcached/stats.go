@@ -0,0 +1,157 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package memcached 通过 memcached 文本协议读取 operand 的运行状态
+package memcached
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Stats 是单个 memcached 实例的统计信息，来自 stats 和 stats settings 命令
+type Stats struct {
+	// GetHits 是命中的 get 请求数
+	GetHits int64
+	// GetMisses 是未命中的 get 请求数
+	GetMisses int64
+	// Evictions 是为了释放内存而被淘汰的有效 item 数
+	Evictions int64
+	// CurrItems 是当前存储的 item 数
+	CurrItems int64
+	// Bytes 是当前 item 使用的内存字节数
+	Bytes int64
+	// LimitMaxBytes 是 memcached 可用于存储的最大字节数（-m）
+	LimitMaxBytes int64
+	// CurrConnections 是当前打开的连接数
+	CurrConnections int64
+	// MaxConnections 是允许的最大连接数（-c），来自 stats settings
+	MaxConnections int64
+}
+
+// FetchStats 连接到 addr 上的 memcached，依次执行 stats 和 stats settings 并解析结果。
+// tlsConfig 不为空时通过 TLS 连接；credentials 不为空时先通过 SASL 认证，
+// 开启 SASL 的 memcached 不接受文本协议，此时改用二进制协议。ctx 的 deadline 同时作用于建立连接和读写。
+func FetchStats(ctx context.Context, addr string, tlsConfig *tls.Config, credentials *Credentials) (*Stats, error) {
+	conn, err := dial(ctx, addr, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	run := runStats
+	if credentials != nil {
+		if err := authenticate(rw, credentials); err != nil {
+			return nil, err
+		}
+		run = runBinaryStats
+	}
+	general, err := run(rw, "stats")
+	if err != nil {
+		return nil, err
+	}
+	settings, err := run(rw, "stats settings")
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{}
+	for _, field := range []struct {
+		values map[string]string
+		name   string
+		dst    *int64
+	}{
+		{general, "get_hits", &stats.GetHits},
+		{general, "get_misses", &stats.GetMisses},
+		{general, "evictions", &stats.Evictions},
+		{general, "curr_items", &stats.CurrItems},
+		{general, "bytes", &stats.Bytes},
+		{general, "limit_maxbytes", &stats.LimitMaxBytes},
+		{general, "curr_connections", &stats.CurrConnections},
+		{settings, "maxconns", &stats.MaxConnections},
+	} {
+		value, ok := field.values[field.name]
+		if !ok {
+			continue
+		}
+		if *field.dst, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid value %q of stat %s: %w", value, field.name, err)
+		}
+	}
+	return stats, nil
+}
+
+// dial 连接到 addr 上的 memcached，tlsConfig 不为空时完成 TLS 握手后再返回。
+// ctx 的 deadline 会设置到连接上，同时作用于之后的读写。
+func dial(ctx context.Context, addr string, tlsConfig *tls.Config) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	if tlsConfig != nil {
+		dialer := &tls.Dialer{Config: tlsConfig}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// runStats 发送一条 stats 命令，读取 "STAT <name> <value>" 行直到 END
+func runStats(rw *bufio.ReadWriter, command string) (map[string]string, error) {
+	if _, err := rw.WriteString(command + "\r\n"); err != nil {
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "END":
+			return values, nil
+		case strings.HasPrefix(line, "STAT "):
+			fields := strings.SplitN(line, " ", 3)
+			if len(fields) != 3 {
+				return nil, fmt.Errorf("malformed response to %q: %q", command, line)
+			}
+			values[fields[1]] = fields[2]
+		default:
+			// ERROR、CLIENT_ERROR 和 SERVER_ERROR 都表示命令失败
+			return nil, fmt.Errorf("%q failed: %s", command, line)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memcached
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/swxfll/operator-sdk-demo/internal/memcached/memcachedtest"
+)
+
+var _ = Describe("FetchStats", func() {
+	var server *memcachedtest.Server
+
+	BeforeEach(func() {
+		var err error
+		server, err = memcachedtest.NewServer(map[string]string{
+			"pid":              "1",
+			"version":          "1.6.23",
+			"get_hits":         "90",
+			"get_misses":       "10",
+			"evictions":        "3",
+			"curr_items":       "42",
+			"bytes":            "1048576",
+			"limit_maxbytes":   "67108864",
+			"curr_connections": "12",
+		}, map[string]string{
+			"maxconns":  "1024",
+			"evictions": "on",
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(server.Close()).To(Succeed())
+	})
+
+	It("should parse stats and stats settings", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		stats, err := FetchStats(ctx, server.Addr, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats).To(Equal(&Stats{
+			GetHits:         90,
+			GetMisses:       10,
+			Evictions:       3,
+			CurrItems:       42,
+			Bytes:           1048576,
+			LimitMaxBytes:   67108864,
+			CurrConnections: 12,
+			MaxConnections:  1024,
+		}))
+	})
+
+	It("should report malformed values", func() {
+		server.SetStat("evictions", "many")
+
+		_, err := FetchStats(context.Background(), server.Addr, nil, nil)
+		Expect(err).To(MatchError(ContainSubstring("evictions")))
+	})
+
+	It("should fail when nothing listens on the address", func() {
+		addr := server.Addr
+		Expect(server.Close()).To(Succeed())
+		server, _ = memcachedtest.NewServer(nil, nil)
+
+		_, err := FetchStats(context.Background(), addr, nil, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,63 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memcached
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
+
+// ReloadCertificate 通过 TLS 连接到 addr 上的 memcached，如果它提供的证书不是 want，
+// 执行 refresh_certs 让 memcached 从磁盘重新加载证书和私钥，返回是否执行了重新加载。
+// 挂载的 Secret 由 kubelet 异步更新，重新加载时文件可能还是旧的，调用者应该稍后再次检查。
+func ReloadCertificate(ctx context.Context, addr string, tlsConfig *tls.Config, want *x509.Certificate) (bool, error) {
+	conn, err := dial(ctx, addr, tlsConfig)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return false, fmt.Errorf("connection to %s is not a TLS connection", addr)
+	}
+	peers := tlsConn.ConnectionState().PeerCertificates
+	if len(peers) > 0 && bytes.Equal(peers[0].Raw, want.Raw) {
+		return false, nil
+	}
+
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	if _, err := rw.WriteString("refresh_certs\r\n"); err != nil {
+		return false, err
+	}
+	if err := rw.Flush(); err != nil {
+		return false, err
+	}
+	line, err := rw.ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+	if line = strings.TrimRight(line, "\r\n"); line != "OK" {
+		return false, fmt.Errorf("refresh_certs failed: %s", line)
+	}
+	return true, nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memcached
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/swxfll/operator-sdk-demo/internal/memcached/memcachedtest"
+)
+
+// newCertificate 生成一个 localhost 的自签名证书
+func newCertificate(serial int64) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	leaf, err := x509.ParseCertificate(der)
+	Expect(err).NotTo(HaveOccurred())
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+var _ = Describe("TLS", func() {
+	var (
+		server *memcachedtest.Server
+		cert   tls.Certificate
+		roots  *x509.CertPool
+	)
+
+	BeforeEach(func() {
+		cert = newCertificate(1)
+		roots = x509.NewCertPool()
+		roots.AddCert(cert.Leaf)
+
+		var err error
+		server, err = memcachedtest.NewTLSServer(map[string]string{"get_hits": "7"}, nil, cert)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(server.Close()).To(Succeed())
+	})
+
+	It("should fetch stats over TLS", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		stats, err := FetchStats(ctx, server.Addr, &tls.Config{RootCAs: roots, ServerName: "localhost"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats.GetHits).To(Equal(int64(7)))
+	})
+
+	It("should reload the certificate only when memcached serves another one", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		rotated := newCertificate(2)
+		roots.AddCert(rotated.Leaf)
+		config := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+
+		reloaded, err := ReloadCertificate(ctx, server.Addr, config, cert.Leaf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reloaded).To(BeFalse())
+		Expect(server.CertRefreshes()).To(BeZero())
+
+		server.SetCertificate(rotated)
+		reloaded, err = ReloadCertificate(ctx, server.Addr, config, rotated.Leaf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reloaded).To(BeTrue())
+		Expect(server.CertRefreshes()).To(Equal(1))
+
+		reloaded, err = ReloadCertificate(ctx, server.Addr, config, rotated.Leaf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reloaded).To(BeFalse())
+	})
+})
@@ -0,0 +1,151 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client 为 Go 服务提供 Swxfll 实例的服务发现。
+//
+// Watcher 监听一个 Swxfll 的状态，并将就绪 Pod 的服务器列表持续同步到 Selector；
+// Selector 使用与 libketama 兼容的一致性哈希为 key 选择服务器，并实现了
+// github.com/bradfitz/gomemcache/memcache.ServerSelector 接口，可以直接用于 memcache.NewFromSelector。
+package client
+
+import (
+	"crypto/md5"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// pointsPerServer 是每个服务器在哈希环上的点数，与 libketama 在权重相同时的取值一致
+const pointsPerServer = 160
+
+// ErrNoServers 在服务器列表为空时由 PickServer 返回
+var ErrNoServers = errors.New("swxfll: no servers available")
+
+// Addr 是服务器的地址。host 可能是 StatefulSet Pod 的 DNS 名称，在建立连接时才解析，
+// 因此不使用 net.TCPAddr。
+type Addr string
+
+// Network 实现 net.Addr
+func (a Addr) Network() string { return "tcp" }
+
+// String 实现 net.Addr，返回 host:port
+func (a Addr) String() string { return string(a) }
+
+// point 是哈希环上的一个点
+type point struct {
+	hash   uint32
+	server Addr
+}
+
+// Selector 是线程安全的一致性哈希服务器选择器。
+// 服务器列表变化时，只有原本落在被移除服务器上（或将要落在新增服务器上）的 key 会被重新映射。
+type Selector struct {
+	mu      sync.RWMutex
+	servers []Addr
+	ring    []point
+}
+
+// NewSelector 返回使用给定 host:port 列表的 Selector
+func NewSelector(servers ...string) *Selector {
+	s := &Selector{}
+	s.SetServers(servers...)
+	return s
+}
+
+// SetServers 替换服务器列表并重建哈希环，顺序和重复项不影响结果
+func (s *Selector) SetServers(servers ...string) {
+	unique := make(map[string]struct{}, len(servers))
+	for _, server := range servers {
+		unique[server] = struct{}{}
+	}
+	addrs := make([]Addr, 0, len(unique))
+	for server := range unique {
+		addrs = append(addrs, Addr(server))
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+
+	ring := make([]point, 0, len(addrs)*pointsPerServer)
+	for _, addr := range addrs {
+		// 与 libketama 相同：对 "host:port-i" 求 MD5，每个摘要产生 4 个点
+		for i := 0; i < pointsPerServer/4; i++ {
+			digest := md5.Sum([]byte(string(addr) + "-" + strconv.Itoa(i)))
+			for h := 0; h < 4; h++ {
+				ring = append(ring, point{hash: digestHash(digest[h*4:]), server: addr})
+			}
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool {
+		if ring[i].hash != ring[j].hash {
+			return ring[i].hash < ring[j].hash
+		}
+		return ring[i].server < ring[j].server
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.servers = addrs
+	s.ring = ring
+}
+
+// Servers 返回当前的服务器列表，按字典序排序
+func (s *Selector) Servers() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	servers := make([]string, len(s.servers))
+	for i, addr := range s.servers {
+		servers[i] = string(addr)
+	}
+	return servers
+}
+
+// PickServer 返回负责 key 的服务器，实现 memcache.ServerSelector
+func (s *Selector) PickServer(key string) (net.Addr, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.ring) == 0 {
+		return nil, ErrNoServers
+	}
+
+	digest := md5.Sum([]byte(key))
+	hash := digestHash(digest[:])
+	// 顺时针找到第一个不小于 key 哈希值的点，超过最后一个点时回到环的起点
+	i := sort.Search(len(s.ring), func(i int) bool { return s.ring[i].hash >= hash })
+	if i == len(s.ring) {
+		i = 0
+	}
+	return s.ring[i].server, nil
+}
+
+// Each 依次对每个服务器调用 f，直到 f 返回错误，实现 memcache.ServerSelector
+func (s *Selector) Each(f func(net.Addr) error) error {
+	s.mu.RLock()
+	servers := s.servers
+	s.mu.RUnlock()
+
+	for _, addr := range servers {
+		if err := f(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// digestHash 将 MD5 摘要的 4 个字节按小端序转换为哈希值，与 libketama 一致
+func digestHash(b []byte) uint32 {
+	return uint32(b[3])<<24 | uint32(b[2])<<16 | uint32(b[1])<<8 | uint32(b[0])
+}
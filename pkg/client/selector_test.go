@@ -0,0 +1,117 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Selector", func() {
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("session:%d", i)
+	}
+
+	// assignments 返回每个 key 被分配到的服务器
+	assignments := func(s *Selector) map[string]string {
+		picked := make(map[string]string, len(keys))
+		for _, key := range keys {
+			addr, err := s.PickServer(key)
+			Expect(err).NotTo(HaveOccurred())
+			picked[key] = addr.String()
+		}
+		return picked
+	}
+
+	It("should fail without servers", func() {
+		_, err := NewSelector().PickServer("key")
+		Expect(err).To(MatchError(ErrNoServers))
+	})
+
+	It("should not depend on the order or duplicates of the servers", func() {
+		a := NewSelector("10.0.0.1:11211", "10.0.0.2:11211", "10.0.0.3:11211")
+		b := NewSelector("10.0.0.3:11211", "10.0.0.1:11211", "10.0.0.2:11211", "10.0.0.1:11211")
+		Expect(assignments(a)).To(Equal(assignments(b)))
+		Expect(b.Servers()).To(Equal([]string{"10.0.0.1:11211", "10.0.0.2:11211", "10.0.0.3:11211"}))
+	})
+
+	It("should spread the keys over every server", func() {
+		counts := map[string]int{}
+		for _, server := range assignments(NewSelector("a:11211", "b:11211", "c:11211")) {
+			counts[server]++
+		}
+		Expect(counts).To(HaveLen(3))
+		for server, n := range counts {
+			Expect(n).To(BeNumerically(">", len(keys)/5), server)
+		}
+	})
+
+	It("should only remap the keys of a removed server", func() {
+		s := NewSelector("a:11211", "b:11211", "c:11211")
+		before := assignments(s)
+		s.SetServers("a:11211", "c:11211")
+		after := assignments(s)
+
+		for _, key := range keys {
+			if before[key] != "b:11211" {
+				Expect(after[key]).To(Equal(before[key]), key)
+			}
+		}
+	})
+
+	It("should return addresses that can be dialed without resolving them first", func() {
+		addr, err := NewSelector("cache-0.cache-headless.default.svc:11211").PickServer("key")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addr.Network()).To(Equal("tcp"))
+		Expect(addr.String()).To(Equal("cache-0.cache-headless.default.svc:11211"))
+
+		var each []string
+		Expect(NewSelector("b:1", "a:1").Each(func(addr net.Addr) error {
+			each = append(each, addr.String())
+			return nil
+		})).To(Succeed())
+		Expect(each).To(Equal([]string{"a:1", "b:1"}))
+	})
+
+	It("should be safe for concurrent use", func() {
+		s := NewSelector("a:11211")
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				defer GinkgoRecover()
+				s.SetServers("a:11211", fmt.Sprintf("b%d:11211", i))
+			}(i)
+			go func() {
+				defer wg.Done()
+				defer GinkgoRecover()
+				for _, key := range keys[:100] {
+					_, err := s.PickServer(key)
+					Expect(err).NotTo(HaveOccurred())
+				}
+			}()
+		}
+		wg.Wait()
+		Expect(s.Servers()).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,157 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+// Watcher 监听一个 Swxfll，并在它的服务器列表变化时更新 Selector。
+// 只需要对该 Swxfll 所在命名空间中 swxflls 资源的 get、list 和 watch 权限。
+type Watcher struct {
+	name     string
+	cache    cache.Cache
+	selector *Selector
+
+	mu       sync.Mutex
+	onUpdate []func(servers []string)
+}
+
+// NewWatcher 返回监听 namespace/name 的 Watcher，调用 Start 后开始同步
+func NewWatcher(cfg *rest.Config, namespace, name string) (*Watcher, error) {
+	scheme := runtime.NewScheme()
+	if err := cachev1beta1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+
+	// 只缓存这一个 Swxfll，不会 list 命名空间中的其他实例
+	c, err := cache.New(cfg, cache.Options{
+		Scheme:            scheme,
+		DefaultNamespaces: map[string]cache.Config{namespace: {}},
+		ByObject: map[client.Object]cache.ByObject{
+			&cachev1beta1.Swxfll{}: {Field: fields.OneTermEqualSelector("metadata.name", name)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Watcher{
+		name:     name,
+		cache:    c,
+		selector: NewSelector(),
+	}, nil
+}
+
+// Selector 返回由 Watcher 持续更新的 Selector。Start 同步完成之前它没有任何服务器。
+func (w *Watcher) Selector() *Selector {
+	return w.selector
+}
+
+// OnUpdate 注册在服务器列表变化时调用的回调，必须在 Start 之前调用
+func (w *Watcher) OnUpdate(f func(servers []string)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onUpdate = append(w.onUpdate, f)
+}
+
+// Start 开始监听，并一直阻塞到 ctx 被取消
+func (w *Watcher) Start(ctx context.Context) error {
+	informer, err := w.cache.GetInformer(ctx, &cachev1beta1.Swxfll{})
+	if err != nil {
+		return err
+	}
+	if _, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { w.update(obj) },
+		UpdateFunc: func(_, obj interface{}) { w.update(obj) },
+		// Swxfll 被删除后它的 Pod 也会被删除，不再返回任何服务器
+		DeleteFunc: func(interface{}) { w.setServers(nil) },
+	}); err != nil {
+		return err
+	}
+	return w.cache.Start(ctx)
+}
+
+// WaitForSync 阻塞到 Watcher 完成首次同步，ctx 被取消时返回错误。
+// 同步完成后如果 Swxfll 存在，Selector 中已经包含它的服务器列表。
+func (w *Watcher) WaitForSync(ctx context.Context) error {
+	if !w.cache.WaitForCacheSync(ctx) {
+		return fmt.Errorf("swxfll: cache for %s did not sync: %w", w.name, ctx.Err())
+	}
+	return nil
+}
+
+// update 从 Swxfll 的状态中读取服务器列表
+func (w *Watcher) update(obj interface{}) {
+	swxfll, ok := obj.(*cachev1beta1.Swxfll)
+	if !ok {
+		return
+	}
+	w.setServers(serversForSwxfll(swxfll))
+}
+
+// setServers 更新 Selector，并在列表变化时调用回调
+func (w *Watcher) setServers(servers []string) {
+	if equalServers(w.selector.Servers(), servers) {
+		return
+	}
+	w.selector.SetServers(servers...)
+
+	w.mu.Lock()
+	callbacks := w.onUpdate
+	w.mu.Unlock()
+	for _, f := range callbacks {
+		f(w.selector.Servers())
+	}
+}
+
+// serversForSwxfll 返回 Swxfll 发布的服务器列表。
+// 优先使用 status.serverList，其中 StatefulSet 的 Pod 使用稳定的 DNS 名称；旧版本 operator 只设置了 status.endpoints。
+func serversForSwxfll(swxfll *cachev1beta1.Swxfll) []string {
+	if list := swxfll.Status.ServerList; list != nil {
+		return list.Servers
+	}
+	return swxfll.Status.Endpoints
+}
+
+// equalServers 判断 got 是否与 want 包含相同的服务器，want 可能未排序或包含重复项
+func equalServers(got, want []string) bool {
+	set := make(map[string]struct{}, len(want))
+	for _, server := range want {
+		set[server] = struct{}{}
+	}
+	if len(set) != len(got) {
+		return false
+	}
+	for _, server := range got {
+		if _, ok := set[server]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1beta1 "github.com/swxfll/operator-sdk-demo/api/v1beta1"
+)
+
+var _ = Describe("Watcher", func() {
+	const name = "sessions"
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		swxfll *cachev1beta1.Swxfll
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+
+		swxfll = &cachev1beta1.Swxfll{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       cachev1beta1.SwxfllSpec{Size: 2},
+		}
+		Expect(k8sClient.Create(ctx, swxfll)).To(Succeed())
+		swxfll.Status.ServerList = &cachev1beta1.ServerListStatus{
+			ConfigMap: name + "-servers",
+			Servers:   []string{"10.0.0.1:11211", "10.0.0.2:11211"},
+			Hash:      "initial",
+		}
+		Expect(k8sClient.Status().Update(ctx, swxfll)).To(Succeed())
+
+		// 同一命名空间中的其他实例不应影响 Watcher
+		other := &cachev1beta1.Swxfll{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+			Spec:       cachev1beta1.SwxfllSpec{Size: 1},
+		}
+		Expect(k8sClient.Create(ctx, other)).To(Succeed())
+		other.Status.Endpoints = []string{"10.0.0.9:11211"}
+		Expect(k8sClient.Status().Update(ctx, other)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		cancel()
+		for _, n := range []string{name, "other"} {
+			obj := &cachev1beta1.Swxfll{ObjectMeta: metav1.ObjectMeta{Name: n, Namespace: "default"}}
+			Expect(k8sClient.Delete(context.Background(), obj)).To(Succeed())
+		}
+	})
+
+	It("should keep the selector in sync with the status of the Swxfll", func() {
+		watcher, err := NewWatcher(cfg, "default", name)
+		Expect(err).NotTo(HaveOccurred())
+		updates := make(chan []string, 10)
+		watcher.OnUpdate(func(servers []string) { updates <- servers })
+
+		go func() {
+			defer GinkgoRecover()
+			Expect(watcher.Start(ctx)).To(Succeed())
+		}()
+		Expect(watcher.WaitForSync(ctx)).To(Succeed())
+
+		Eventually(watcher.Selector().Servers).Should(Equal([]string{"10.0.0.1:11211", "10.0.0.2:11211"}))
+		Eventually(updates).Should(Receive(HaveLen(2)))
+
+		By("removing a pod from the server list")
+		swxfll.Status.ServerList.Servers = []string{"10.0.0.2:11211"}
+		swxfll.Status.ServerList.Hash = "scaled-down"
+		Expect(k8sClient.Status().Update(ctx, swxfll)).To(Succeed())
+		Eventually(watcher.Selector().Servers).Should(Equal([]string{"10.0.0.2:11211"}))
+
+		addr, err := watcher.Selector().PickServer("session:1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addr.String()).To(Equal("10.0.0.2:11211"))
+	})
+})